@@ -16,4 +16,12 @@ run-go:
 	$(Q)CGO_ENABLED=0 \
 		go ${GO_COMMAND} ${V_FLAG} \
 		-ldflags "-X ${GO_PACKAGE_PATH}/pkg/version.Commit=${GIT_COMMIT_ID} -X ${GO_PACKAGE_PATH}/pkg/version.BuildTime=${BUILD_TIME}" \
-        ${GO_EXTRA_FLAGS} ${GO_PACKAGE_PATH}/cmd/goupgrader/...
\ No newline at end of file
+        ${GO_EXTRA_FLAGS} ${GO_PACKAGE_PATH}/cmd/goupgrader/...
+
+SEED_MATRIX_FROM ?= 4.14
+SEED_MATRIX_TO ?= 4.18
+
+.PHONY: seed-matrix
+## refreshes the compatibility matrix built into the binary
+seed-matrix:
+	$(Q)go run ./cmd/goupgrader matrix refresh --from=$(SEED_MATRIX_FROM) --to=$(SEED_MATRIX_TO) --matrix=pkg/cmd/seed_matrix.yaml
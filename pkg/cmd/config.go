@@ -1,38 +1,357 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"go/version"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/rs/zerolog/log"
+	"github.com/rsoaresd/goupgrader/pkg/cmd/flags"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
 	"gopkg.in/yaml.v2"
 )
 
-// parseConfig parses the YAML configuration file at the given path
-// and validates the dependencies specified in the file.
-func parseConfig(configPath string) (*Config, error) {
-	// open and read the YAML file
-	yamlFile, err := os.Open(configPath)
+func NewConfig() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect upgrade configs",
+		Args:  cobra.ExactArgs(0),
+	}
+	command.AddCommand(NewConfigRender())
+	command.AddCommand(NewConfigValidate())
+
+	return command
+}
+
+func NewConfigRender() *cobra.Command {
+	var config string
+	var options ConfigOptions
+
+	command := &cobra.Command{
+		Use:   "render --config=<config-path>",
+		Short: "Print a config merged with the configs it includes",
+		Long: `Prints the config as upgrade sees it: the configs listed in its include section, and the ones they
+include, are merged in order, with the config itself applied last, and its variables are expanded.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return RenderConfig(cmd.OutOrStdout(), config, options)
+		},
+	}
+	command.Flags().StringVarP(&config, "config", "c", "", "path or URL of the config, or - to read it from stdin")
+	flags.MustMarkRequired(command, "config")
+	addConfigFlags(command, &options)
+
+	return command
+}
+
+func NewConfigValidate() *cobra.Command {
+	var config string
+	var options ConfigOptions
+	var remote bool
+
+	command := &cobra.Command{
+		Use:   "validate --config=<config-path> [--remote]",
+		Short: "Check a config without upgrading anything",
+		Long: `Parses the config, merged with the configs it includes, and checks it the way upgrade does: unknown
+fields, e.g. a misspelled 'verison', undefined variables and invalid dependencies are reported.
+
+With --remote, also checks that each module exists on the Go module proxy, that each version is published
+and that each branch exists in the git repository of its module, reporting all problems at once.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			parsed, err := parseConfig(config, options)
+			if err != nil {
+				return err
+			}
+			if remote {
+				dependencies, _ := liveDependencies(parsed)
+				if err := validateRemote(dependencies); err != nil {
+					return err
+				}
+			}
+			log.Info().Msgf("%s is valid", config)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&config, "config", "c", "", "path or URL of the config, or - to read it from stdin")
+	flags.MustMarkRequired(command, "config")
+	addConfigFlags(command, &options)
+	command.Flags().BoolVar(&remote, "remote", false, "also check the modules, versions and branches against the module proxy and git")
+
+	return command
+}
+
+// RenderConfig writes the config at the given location, merged with the configs it includes and expanded, in the
+// format of the config.
+func RenderConfig(out io.Writer, configPath string, options ConfigOptions) error {
+	config, err := parseConfig(configPath, options)
+	if err != nil {
+		return err
+	}
+
+	format, err := configFormat(configPath, options.Format)
+	if err != nil {
+		return err
+	}
+	data, err := encodeConfig(config, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	return err
+}
+
+// parseConfig parses the YAML configuration file at the given path, merged with the configs it includes,
+// expands the ${VAR} variables and {{ }} templates of its values, and validates the dependencies specified in the result.
+func parseConfig(configPath string, options ConfigOptions) (*Config, error) {
+	config, err := loadConfig(configPath, nil, options)
 	if err != nil {
 		return nil, err
 	}
-	defer yamlFile.Close()
 
-	// parse the YAML file
-	var config Config
-	decoder := yaml.NewDecoder(yamlFile)
-	if err := decoder.Decode(&config); err != nil {
+	if err := expandConfig(config, options); err != nil {
 		return nil, err
 	}
 
 	// validate config
+	if err := validateGoConfig(config.Go); err != nil {
+		return nil, err
+	}
+	if err := validateExclusions(config); err != nil {
+		return nil, err
+	}
 	for _, dep := range config.Dependencies {
 		if err := validateDependency(dep); err != nil {
 			return nil, err
 		}
 	}
 
-	return &config, nil
+	return config, nil
+}
+
+// loadConfig reads the config at the given location, a local path or a URL, and layers it on top of the configs it
+// includes: the included configs are applied in order, each one overriding the previous ones, and the config itself
+// is applied last. A dependency overrides the one with the same package, and a go section overrides the previous one.
+// including lists the configs being loaded that include this one, to detect cycles.
+// The ${VAR} variables of the includes are expanded with the vars block of the config including them.
+func loadConfig(location string, including []string, options ConfigOptions) (*Config, error) {
+	for _, parent := range including {
+		if parent == location {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(including, " -> "), location)
+		}
+	}
+
+	data, err := readConfigSource(location)
+	if err != nil {
+		return nil, err
+	}
+
+	// the format given on the command line only applies to the config itself, not to the configs it includes
+	format := options.Format
+	if len(including) > 0 {
+		format = ""
+	}
+	if format, err = configFormat(location, format); err != nil {
+		return nil, err
+	}
+	config, err := decodeConfig(data, location, format)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Config{}
+	expander := newConfigExpander(config.Vars, options)
+	for _, include := range config.Include {
+		include, err := expander.expandVariables(include)
+		if err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+		included, err := loadConfig(resolveInclude(location, include), append(including, location), options)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		}
+		merged = overlayConfig(merged, included)
+	}
+	config.Include = nil
+
+	return overlayConfig(merged, config), nil
+}
+
+// unknownFieldPattern matches the errors of yaml.v2 about unknown fields, e.g. "line 4: field verison not found in type cmd.Dependency"
+var unknownFieldPattern = regexp.MustCompile(`^(line \d+): field (\S+) not found in type \S+$`)
+
+// configDecodeError describes the errors of decoding the config at the given location, one per line, e.g.
+// "config.yaml: line 4: unknown field "verison"".
+func configDecodeError(location string, err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return fmt.Errorf("%s: %w", location, err)
+	}
+
+	var errs []error
+	for _, msg := range typeErr.Errors {
+		if match := unknownFieldPattern.FindStringSubmatch(msg); match != nil {
+			msg = fmt.Sprintf("%s: unknown field %q", match[1], match[2])
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", location, msg))
+	}
+	return errors.Join(errs...)
+}
+
+// stdioPath is the path standing for stdin as --config and for stdout as --output
+const stdioPath = "-"
+
+// stdout is where a config given as "-" is written to, replaceable in tests
+var stdout io.Writer = os.Stdout
+
+// readStdin reads the config given as "-" from stdin, once: upgrade parses the config more than once
+var readStdin = sync.OnceValues(func() ([]byte, error) {
+	return io.ReadAll(os.Stdin)
+})
+
+// readConfigSource reads the config at the given location, fetching it if it is an http(s) URL
+// and reading it from stdin if it is "-".
+func readConfigSource(location string) ([]byte, error) {
+	if location == stdioPath {
+		return readStdin()
+	}
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+
+	resp, err := http.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non 200 response: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveInclude returns the location of an include, relative to the location of the config including it,
+// or to the current directory for a config read from stdin.
+func resolveInclude(location, include string) string {
+	if isURL(include) || filepath.IsAbs(include) {
+		return include
+	}
+
+	if isURL(location) {
+		base, err := url.Parse(location)
+		if err != nil {
+			return include
+		}
+		ref, err := url.Parse(include)
+		if err != nil {
+			return include
+		}
+		return base.ResolveReference(ref).String()
+	}
+
+	return filepath.Join(filepath.Dir(location), include)
+}
+
+// overlayConfig returns the base config overridden by the overlay config: the go section of the overlay replaces
+// the one of the base, the vars of the overlay replace the ones of the base with the same name, and each dependency
+// of the overlay replaces the one of the base with the same package, in place, or is appended. The exclusions and
+// skipped versions of both configs add up.
+func overlayConfig(base, overlay *Config) *Config {
+	result := &Config{Go: base.Go}
+	if overlay.Go != nil {
+		result.Go = overlay.Go
+	}
+
+	for _, pattern := range append(append([]string{}, base.Exclude...), overlay.Exclude...) {
+		if !slices.Contains(result.Exclude, pattern) {
+			result.Exclude = append(result.Exclude, pattern)
+		}
+	}
+	for _, skipVersions := range []map[string][]string{base.SkipVersions, overlay.SkipVersions} {
+		for modulePath, versions := range skipVersions {
+			if result.SkipVersions == nil {
+				result.SkipVersions = map[string][]string{}
+			}
+			for _, version := range versions {
+				if !slices.Contains(result.SkipVersions[modulePath], version) {
+					result.SkipVersions[modulePath] = append(result.SkipVersions[modulePath], version)
+				}
+			}
+		}
+	}
+
+	for _, vars := range []map[string]string{base.Vars, overlay.Vars} {
+		for name, value := range vars {
+			if result.Vars == nil {
+				result.Vars = map[string]string{}
+			}
+			result.Vars[name] = value
+		}
+	}
+
+	positions := map[string]int{}
+	for _, dep := range append(append([]Dependency{}, base.Dependencies...), overlay.Dependencies...) {
+		if position, found := positions[dep.Package]; found {
+			result.Dependencies[position] = dep
+			continue
+		}
+		positions[dep.Package] = len(result.Dependencies)
+		result.Dependencies = append(result.Dependencies, dep)
+	}
+
+	return result
+}
+
+// isExcluded returns true if the module matches one of the exclude patterns of the config.
+func (c *Config) isExcluded(modulePath string) bool {
+	for _, pattern := range c.Exclude {
+		if matched, _ := path.Match(pattern, modulePath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isSkippedVersion returns true if the version of the module is one of the skipped versions of the config.
+func (c *Config) isSkippedVersion(modulePath, version string) bool {
+	return slices.Contains(c.SkipVersions[modulePath], version)
+}
+
+// validateExclusions checks that the exclude patterns and the skipped versions of the config are valid.
+func validateExclusions(config *Config) error {
+	for _, pattern := range config.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("exclude: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	for modulePath, versions := range config.SkipVersions {
+		for _, version := range versions {
+			if !semver.IsValid(version) {
+				return fmt.Errorf("skipVersions: invalid version %q of %s", version, modulePath)
+			}
+		}
+	}
+
+	return nil
 }
 
 // validateDependency checks if a dependency has valid version or branch attributes.
@@ -52,6 +371,11 @@ func validateDependency(dependency Dependency) error {
 		return fmt.Errorf("dependency %s: version cannot be an empty string", dependency.Package)
 	}
 
+	// the source branch only documents where a pinned version comes from
+	if dependency.SourceBranch != "" && dependency.Version == "" {
+		return fmt.Errorf("dependency %s: sourceBranch can only be set together with version", dependency.Package)
+	}
+
 	// if branch is specified, it should be a non-empty string
 	if dependency.Branch != "" && strings.TrimSpace(dependency.Branch) == "" {
 		return fmt.Errorf("dependency %s: branch cannot be an empty string", dependency.Package)
@@ -59,3 +383,24 @@ func validateDependency(dependency Dependency) error {
 
 	return nil
 }
+
+// validateGoConfig checks if the go section of the config holds valid go versions.
+func validateGoConfig(goConfig *GoConfig) error {
+	if goConfig == nil {
+		return nil
+	}
+
+	if goConfig.MaxVersion != "" && !version.IsValid("go"+goConfig.MaxVersion) {
+		return fmt.Errorf("go: invalid maxVersion %q", goConfig.MaxVersion)
+	}
+
+	if goConfig.Toolchain != "" && !version.IsValid(goConfig.Toolchain) {
+		return fmt.Errorf("go: invalid toolchain %q", goConfig.Toolchain)
+	}
+
+	if goConfig.Toolchain != "" && !goConfig.Apply {
+		return fmt.Errorf("go: toolchain can only be set when apply is enabled")
+	}
+
+	return nil
+}
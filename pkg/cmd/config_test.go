@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +33,7 @@ func TestParseConfig(t *testing.T) {
 		require.NoError(t, err)
 		require.NoError(t, tmpFile.Close()) // make sure it's flushed and closed
 
-		parsedConfig, err := parseConfig(tmpFile.Name())
+		parsedConfig, err := parseConfig(tmpFile.Name(), ConfigOptions{})
 		require.NoError(t, err)
 
 		assert.Len(t, parsedConfig.Dependencies, 2)
@@ -49,12 +57,12 @@ func TestParseConfig(t *testing.T) {
 		require.NoError(t, err)
 		require.NoError(t, tmpFile.Close()) // make sure it's flushed and closed
 
-		_, err = parseConfig(tmpFile.Name())
+		_, err = parseConfig(tmpFile.Name(), ConfigOptions{})
 		require.Error(t, err)
 	})
 
 	t.Run("non-existent config path", func(t *testing.T) {
-		_, err := parseConfig("/nonexistent/path.yaml")
+		_, err := parseConfig("/nonexistent/path.yaml", ConfigOptions{})
 		require.Error(t, err)
 	})
 }
@@ -68,36 +76,46 @@ func TestValidateDependency(t *testing.T) {
 		// valid cases
 		{
 			name:       "Valid version only",
-			dependency: Dependency{"package1", "v1.0.0", ""},
+			dependency: Dependency{Package: "package1", Version: "v1.0.0"},
 			expected:   "",
 		},
 		{
 			name:       "Valid branch only",
-			dependency: Dependency{"package2", "", "branch1"},
+			dependency: Dependency{Package: "package2", Branch: "branch1"},
+			expected:   "",
+		},
+		{
+			name:       "Valid version pinned from a branch",
+			dependency: Dependency{Package: "package2", Version: "v0.0.0-20250101000000-0123456789ab", SourceBranch: "branch1"},
 			expected:   "",
 		},
 
 		// invalid cases
 		{
 			name:       "Invalid: both version and branch set",
-			dependency: Dependency{"package3", "v1.0.0", "branch1"},
+			dependency: Dependency{Package: "package3", Version: "v1.0.0", Branch: "branch1"},
 			expected:   "dependency package3: cannot specify both version and branch",
 		},
 		{
 			name:       "Invalid: neither version nor branch",
-			dependency: Dependency{"package4", "", ""},
+			dependency: Dependency{Package: "package4"},
 			expected:   "dependency package4: must specify either version or branch",
 		},
 		{
 			name:       "Invalid: empty branch",
-			dependency: Dependency{"package5", "", " "},
+			dependency: Dependency{Package: "package5", Branch: " "},
 			expected:   "dependency package5: branch cannot be an empty string",
 		},
 		{
 			name:       "Invalid: empty version",
-			dependency: Dependency{"package6", " ", ""},
+			dependency: Dependency{Package: "package6", Version: " "},
 			expected:   "dependency package6: version cannot be an empty string",
 		},
+		{
+			name:       "Invalid: source branch without version",
+			dependency: Dependency{Package: "package7", Branch: "branch1", SourceBranch: "branch1"},
+			expected:   "dependency package7: sourceBranch can only be set together with version",
+		},
 	}
 
 	for _, test := range tests {
@@ -113,3 +131,362 @@ func TestValidateDependency(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateGoConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		goConfig *GoConfig
+		expected string
+	}{
+		{
+			name:     "No go section",
+			goConfig: nil,
+			expected: "",
+		},
+		{
+			name:     "Valid go section",
+			goConfig: &GoConfig{Apply: true, Toolchain: "go1.23.4", MaxVersion: "1.23"},
+			expected: "",
+		},
+		{
+			name:     "Invalid: max version",
+			goConfig: &GoConfig{MaxVersion: "v1.23"},
+			expected: "go: invalid maxVersion \"v1.23\"",
+		},
+		{
+			name:     "Invalid: toolchain",
+			goConfig: &GoConfig{Apply: true, Toolchain: "1.23.4"},
+			expected: "go: invalid toolchain \"1.23.4\"",
+		},
+		{
+			name:     "Invalid: toolchain without apply",
+			goConfig: &GoConfig{Toolchain: "go1.23.4"},
+			expected: "go: toolchain can only be set when apply is enabled",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateGoConfig(test.goConfig)
+
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+	writeConfig("base.yaml", `go:
+  maxVersion: "1.22"
+dependencies:
+  - package: "github.com/onsi/ginkgo/v2"
+    version: "v2.19.0"
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.18.5"
+`)
+	writeConfig("openshift/4.18.yaml", `include:
+  - ../base.yaml
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.4"
+  - package: "github.com/openshift/api"
+    branch: "release-4.18"
+`)
+	configPath := writeConfig("repo/config.yaml", `include:
+  - ../openshift/4.18.yaml
+go:
+  maxVersion: "1.23"
+dependencies:
+  - package: "github.com/onsi/ginkgo/v2"
+    version: "v2.20.0"
+`)
+
+	config, err := parseConfig(configPath, ConfigOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Go: &GoConfig{MaxVersion: "1.23"},
+		Dependencies: []Dependency{
+			{Package: "github.com/onsi/ginkgo/v2", Version: "v2.20.0"},
+			{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+			{Package: "github.com/openshift/api", Branch: "release-4.18"},
+		},
+	}, config)
+
+	t.Run("render", func(t *testing.T) {
+		var out bytes.Buffer
+		cmd := NewConfig()
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"render", "--config=" + configPath})
+
+		err := cmd.Execute()
+
+		require.NoError(t, err)
+		assert.Equal(t, `go:
+  maxVersion: "1.23"
+dependencies:
+- package: github.com/onsi/ginkgo/v2
+  version: v2.20.0
+- package: sigs.k8s.io/controller-runtime
+  version: v0.19.4
+- package: github.com/openshift/api
+  branch: release-4.18
+`, out.String())
+	})
+
+	t.Run("remote include", func(t *testing.T) {
+		server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		t.Cleanup(server.Close)
+		configPath := writeConfig("remote.yaml", fmt.Sprintf(`include:
+  - %s/openshift/4.18.yaml
+dependencies: []
+`, server.URL))
+
+		config, err := parseConfig(configPath, ConfigOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{
+			{Package: "github.com/onsi/ginkgo/v2", Version: "v2.19.0"},
+			{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+			{Package: "github.com/openshift/api", Branch: "release-4.18"},
+		}, config.Dependencies)
+	})
+
+	t.Run("include cycle", func(t *testing.T) {
+		first := writeConfig("cycle/first.yaml", "include: [second.yaml]\ndependencies: []\n")
+		second := writeConfig("cycle/second.yaml", "include: [first.yaml]\ndependencies: []\n")
+
+		_, err := parseConfig(first, ConfigOptions{})
+
+		require.EqualError(t, err, fmt.Sprintf("include second.yaml: include first.yaml: include cycle: %s -> %s -> %s", first, second, first))
+	})
+
+	t.Run("missing include", func(t *testing.T) {
+		configPath := writeConfig("missing.yaml", "include: [nonexistent.yaml]\ndependencies: []\n")
+
+		_, err := parseConfig(configPath, ConfigOptions{})
+
+		require.ErrorContains(t, err, "include nonexistent.yaml: open "+filepath.Join(dir, "nonexistent.yaml"))
+	})
+}
+
+func TestParseConfigVariables(t *testing.T) {
+	dir := t.TempDir()
+	matrixPath := filepath.Join(dir, "matrix.yaml")
+	require.NoError(t, saveMatrix(&CompatibilityMatrix{Version: 1, Entries: []MatrixEntry{
+		{Openshift: "4.18", ControllerRuntime: "v0.19.4"},
+	}}, matrixPath))
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`vars:
+  OCP_VERSION: "4.17"
+  GROUP: openshift
+dependencies:
+  - package: "github.com/openshift/api"
+    branch: "release-${OCP_VERSION}"
+    group: "{{ .Vars.GROUP }}"
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "{{ .Matrix.ControllerRuntime }}"
+  - package: "github.com/onsi/ginkgo/v2"
+    version: "${GINKGO_VERSION}"
+`), 0600))
+	t.Setenv("GINKGO_VERSION", "v2.20.0")
+
+	config, err := parseConfig(configPath, ConfigOptions{Vars: map[string]string{"OCP_VERSION": "4.18"}, MatrixPath: matrixPath})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Dependency{
+		{Package: "github.com/openshift/api", Branch: "release-4.18", Group: "openshift"},
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+		{Package: "github.com/onsi/ginkgo/v2", Version: "v2.20.0"},
+	}, config.Dependencies)
+
+	t.Run("the environment overrides the vars block", func(t *testing.T) {
+		t.Setenv("OCP_VERSION", "4.16")
+
+		config, err := parseConfig(configPath, ConfigOptions{MatrixPath: matrixPath})
+
+		require.EqualError(t, err, fmt.Sprintf("dependency sigs.k8s.io/controller-runtime: compatibility matrix %s has no entry for Openshift 4.16, "+
+			"run 'goupgrader matrix refresh --from=4.16' to add it", matrixPath))
+		assert.Nil(t, config)
+	})
+
+	t.Run("same precedence in templates", func(t *testing.T) {
+		configPath := filepath.Join(dir, "precedence.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`vars:
+  GROUP: openshift
+dependencies:
+  - package: "github.com/openshift/api"
+    branch: "${GROUP}"
+    group: "{{ .Vars.GROUP }}"
+`), 0600))
+		t.Setenv("GROUP", "kubernetes")
+
+		config, err := parseConfig(configPath, ConfigOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{{Package: "github.com/openshift/api", Branch: "kubernetes", Group: "kubernetes"}}, config.Dependencies)
+
+		config, err = parseConfig(configPath, ConfigOptions{Vars: map[string]string{"GROUP": "operators"}})
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{{Package: "github.com/openshift/api", Branch: "operators", Group: "operators"}}, config.Dependencies)
+	})
+
+	t.Run("undefined variable", func(t *testing.T) {
+		configPath := filepath.Join(dir, "undefined.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "github.com/openshift/api"
+    branch: "release-${GOUPGRADER_UNDEFINED}"
+`), 0600))
+
+		_, err := parseConfig(configPath, ConfigOptions{})
+
+		require.EqualError(t, err, "dependency github.com/openshift/api: undefined variable GOUPGRADER_UNDEFINED")
+	})
+
+	t.Run("matrix without OpenShift version", func(t *testing.T) {
+		configPath := filepath.Join(dir, "no-version.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "{{ .Matrix.ControllerRuntime }}"
+`), 0600))
+
+		_, err := parseConfig(configPath, ConfigOptions{MatrixPath: matrixPath})
+
+		require.EqualError(t, err, "dependency sigs.k8s.io/controller-runtime: {{ .Matrix }} requires the OCP_VERSION variable")
+	})
+}
+
+func TestParseConfigUnknownFields(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`go:
+  maxVersion: "1.23"
+dependencies:
+  - pacakge: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "github.com/openshift/api"
+    verison: "v0.0.0-20250301100000-0123456789ab"
+`), 0600))
+
+	_, err := parseConfig(configPath, ConfigOptions{})
+
+	require.EqualError(t, err, fmt.Sprintf("%[1]s: line 4: unknown field \"pacakge\"\n%[1]s: line 7: unknown field \"verison\"", configPath))
+
+	t.Run("validate", func(t *testing.T) {
+		cmd := NewConfig()
+		cmd.SetArgs([]string{"validate", "--config=" + configPath})
+
+		err := cmd.Execute()
+
+		require.ErrorContains(t, err, "line 4: unknown field \"pacakge\"")
+	})
+}
+
+// TestConfigSchema checks that the published JSON Schema describes every field of the config, and only those.
+func TestConfigSchema(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "schema", "config.schema.json"))
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	properties := func(object map[string]interface{}) []string {
+		var names []string
+		for name := range object["properties"].(map[string]interface{}) {
+			names = append(names, name)
+		}
+		return names
+	}
+	fields := func(value interface{}) []string {
+		var names []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			names = append(names, strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0])
+		}
+		return names
+	}
+	property := func(object map[string]interface{}, name string) map[string]interface{} {
+		return object["properties"].(map[string]interface{})[name].(map[string]interface{})
+	}
+
+	assert.ElementsMatch(t, fields(Config{}), properties(schema))
+	// like parseConfig, the schema accepts configs without dependencies, e.g. overlays only including other configs
+	assert.NotContains(t, schema, "required")
+	assert.ElementsMatch(t, fields(GoConfig{}), properties(property(schema, "go")))
+	assert.ElementsMatch(t, fields(Dependency{}), properties(property(schema, "dependencies")["items"].(map[string]interface{})))
+}
+
+func TestParseConfigStdin(t *testing.T) {
+	origReadStdin := readStdin
+	t.Cleanup(func() { readStdin = origReadStdin })
+	basePath := filepath.Join(t.TempDir(), "base.json")
+	require.NoError(t, os.WriteFile(basePath, []byte(`{"dependencies": [{"package": "github.com/onsi/ginkgo/v2", "version": "v2.20.0"}]}`), 0600))
+	reads := 0
+	readStdin = func() ([]byte, error) {
+		reads++
+		return []byte(fmt.Sprintf(`include: [%s]
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+`, basePath)), nil
+	}
+
+	config, err := parseConfig("-", ConfigOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Dependency{
+		{Package: "github.com/onsi/ginkgo/v2", Version: "v2.20.0"},
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+	}, config.Dependencies)
+	assert.Equal(t, 1, reads)
+}
+
+func TestWriteGeneratedConfigStdout(t *testing.T) {
+	origStdout := stdout
+	t.Cleanup(func() { stdout = origStdout })
+	var out bytes.Buffer
+	stdout = &out
+
+	err := writeGeneratedConfig(&Config{Dependencies: []Dependency{{Package: "k8s.io/api", Version: "v0.31.1"}}}, "-", GenerateOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "dependencies:\n- package: k8s.io/api\n  version: v0.31.1\n", out.String())
+
+	t.Run("merge", func(t *testing.T) {
+		cmd := NewGenerateConfigForOpenshiftDependencies()
+		cmd.SetArgs([]string{"--target-kubernetes-version=1.31", "--in-use-op-sdk-version=v1.38.0", "--output=-", "--merge"})
+
+		err := cmd.Execute()
+
+		require.EqualError(t, err, "--merge cannot be used when writing the config to stdout")
+	})
+}
+
+func TestValidateExclusions(t *testing.T) {
+	require.NoError(t, validateExclusions(&Config{
+		Exclude:      []string{"github.com/onsi/*", "k8s.io/api"},
+		SkipVersions: map[string][]string{"sigs.k8s.io/controller-runtime": {"v0.20.0"}},
+	}))
+
+	require.EqualError(t, validateExclusions(&Config{Exclude: []string{"github.com/onsi/[ginkgo"}}),
+		`exclude: invalid pattern "github.com/onsi/[ginkgo": syntax error in pattern`)
+
+	require.EqualError(t, validateExclusions(&Config{SkipVersions: map[string][]string{"sigs.k8s.io/controller-runtime": {"0.20.0"}}}),
+		`skipVersions: invalid version "0.20.0" of sigs.k8s.io/controller-runtime`)
+}
+
+func TestOverlayConfigExclusions(t *testing.T) {
+	merged := overlayConfig(
+		&Config{Exclude: []string{"github.com/onsi/*"}, SkipVersions: map[string][]string{"k8s.io/api": {"v0.31.0"}}},
+		&Config{Exclude: []string{"github.com/onsi/*", "k8s.io/klog/v2"}, SkipVersions: map[string][]string{"k8s.io/api": {"v0.31.0", "v0.31.1"}}})
+
+	assert.Equal(t, []string{"github.com/onsi/*", "k8s.io/klog/v2"}, merged.Exclude)
+	assert.Equal(t, map[string][]string{"k8s.io/api": {"v0.31.0", "v0.31.1"}}, merged.SkipVersions)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DistributionOCP        = "ocp"
+	DistributionOKD        = "okd"
+	DistributionMicroShift = "microshift"
+)
+
+// distributions are the OpenShift distributions generate can target
+var distributions = map[string]Distribution{
+	// OCP versions are used as is, e.g. 4.18 for the release-4.18 branch
+	DistributionOCP: {Name: DistributionOCP, Repository: "openshift/api"},
+	// OKD is built from the same release branches as OCP, but its versions look like 4.17.0-okd-scos.0
+	DistributionOKD: {Name: DistributionOKD, Repository: "openshift/api", Normalize: true},
+	// MicroShift rebases on Kubernetes in its own repository, with versions like 4.17.1
+	DistributionMicroShift: {Name: DistributionMicroShift, Repository: "openshift/microshift", Normalize: true},
+}
+
+// releaseVersionPattern matches the X.Y release at the beginning of a distribution version
+var releaseVersionPattern = regexp.MustCompile(`^v?(\d+\.\d+)(?:[.-]|$)`)
+
+// lookupDistribution returns the distribution with the given name, OCP if the name is empty.
+func lookupDistribution(name string) (*Distribution, error) {
+	if name == "" {
+		name = DistributionOCP
+	}
+
+	distribution, found := distributions[name]
+	if !found {
+		var names []string
+		for known := range distributions {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown distribution %q: must be one of %s", name, strings.Join(names, ", "))
+	}
+
+	return &distribution, nil
+}
+
+// releaseVersion returns the X.Y release of the given version of the distribution.
+func (d *Distribution) releaseVersion(version string) (string, error) {
+	if !d.Normalize {
+		return version, nil
+	}
+
+	match := releaseVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("invalid %s version %q: must start with <major>.<minor>", d.Name, version)
+	}
+
+	return match[1], nil
+}
+
+// kubernetesVersion returns the version of k8s.io/api required by the release branch of the given X.Y release.
+func (d *Distribution) kubernetesVersion(release string) (string, error) {
+	return GetKubernetesVersion(d.Repository, fmt.Sprintf("release-%s", release), "k8s.io/api")
+}
+
+// kubernetesDependencies returns the k8s.io/* modules required by the release branch of the given X.Y release,
+// as dependencies of the kubernetes group.
+func (d *Distribution) kubernetesDependencies(release string) ([]Dependency, error) {
+	return getKubernetesDependenciesFromBranch(d.Repository, fmt.Sprintf("release-%s", release))
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistributionReleaseVersion(t *testing.T) {
+	testCases := []struct {
+		distribution string
+		version      string
+		expected     string
+	}{
+		{distribution: DistributionOCP, version: "4.18", expected: "4.18"},
+		{distribution: "", version: "4.18", expected: "4.18"},
+		{distribution: DistributionOKD, version: "4.17.0-okd-scos.0", expected: "4.17"},
+		{distribution: DistributionOKD, version: "4.15.0-0.okd-2024-03-10-010116", expected: "4.15"},
+		{distribution: DistributionOKD, version: "4.17", expected: "4.17"},
+		{distribution: DistributionMicroShift, version: "4.17.1", expected: "4.17"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.distribution+" "+tc.version, func(t *testing.T) {
+			distribution, err := lookupDistribution(tc.distribution)
+			require.NoError(t, err)
+
+			release, err := distribution.releaseVersion(tc.version)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, release)
+		})
+	}
+
+	t.Run("invalid version", func(t *testing.T) {
+		distribution, err := lookupDistribution(DistributionOKD)
+		require.NoError(t, err)
+
+		_, err = distribution.releaseVersion("scos-4.17")
+
+		require.EqualError(t, err, `invalid okd version "scos-4.17": must start with <major>.<minor>`)
+	})
+
+	t.Run("unknown distribution", func(t *testing.T) {
+		_, err := lookupDistribution("rosa")
+		require.EqualError(t, err, `unknown distribution "rosa": must be one of microshift, ocp, okd`)
+	})
+}
+
+func TestGenerateConfigForDistributions(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"github.com/operator-framework/operator-sdk/@v/list": "v1.38.0\nv1.39.1\n",
+	})
+	newFakeGitHubRawContent(t, map[string]string{
+		"openshift/api/release-4.17/go.mod":              openshiftAPIGoMod("v0.30.2"),
+		"openshift/microshift/release-4.17/go.mod":       openshiftAPIGoMod("v0.30.5"),
+		"operator-framework/operator-sdk/v1.38.0/go.mod": fullOperatorSdkGoMod("v0.30.3", "v0.18.5"),
+		"operator-framework/operator-sdk/v1.39.1/go.mod": fullOperatorSdkGoMod("v0.31.2", "v0.19.4"),
+	})
+	profilePath := filepath.Join(t.TempDir(), "profile.yaml")
+	require.NoError(t, os.WriteFile(profilePath, []byte(`dependencies:
+- package: sigs.k8s.io/controller-runtime
+  track: version
+- package: github.com/openshift/api
+  track: branch
+`), 0600))
+
+	t.Run("okd", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "config.yaml")
+		cmd := NewGenerateConfigForOpenshiftDependencies()
+		cmd.SetArgs([]string{"--target-openshift-version=4.17.0-okd-scos.0", "--distribution=okd", "--in-use-op-sdk-version=v1.38.0",
+			"--profile=" + profilePath, "--output=" + output})
+
+		err := cmd.Execute()
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, `dependencies:
+- package: sigs.k8s.io/controller-runtime
+  version: v0.18.5
+- package: github.com/openshift/api
+  branch: release-4.17
+- package: k8s.io/api
+  version: v0.30.2
+  group: kubernetes
+- package: k8s.io/apimachinery
+  version: v0.30.2
+  group: kubernetes
+`, string(content))
+	})
+
+	t.Run("microshift", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "config.yaml")
+
+		err := GenerateConfigForOpenshiftDependencies("4.17.1", "v1.38.0", output, GenerateOptions{Distribution: DistributionMicroShift, ProfilePath: profilePath})
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "- package: github.com/openshift/api\n  branch: release-4.17\n")
+		assert.Contains(t, string(content), "- package: k8s.io/api\n  version: v0.30.5\n")
+	})
+
+	t.Run("microshift is not in the compatibility matrix", func(t *testing.T) {
+		err := GenerateConfigForOpenshiftDependencies("4.17.1", "", filepath.Join(t.TempDir(), "config.yaml"),
+			GenerateOptions{Distribution: DistributionMicroShift, Offline: true})
+
+		require.EqualError(t, err, "--offline cannot be used with the microshift distribution: the compatibility matrix is built from the openshift/api release branches")
+	})
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// matrixVersionVar is the variable holding the OpenShift version whose compatibility matrix entry is {{ .Matrix }}
+const matrixVersionVar = "OCP_VERSION"
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// addConfigFlags adds the flags controlling how the config is read and its variables expanded.
+func addConfigFlags(command *cobra.Command, options *ConfigOptions) {
+	command.Flags().StringVar((*string)(&options.Format), "format", "", "format of the config: yaml, json or toml (defaults to the extension of the config, or yaml)")
+	command.Flags().StringToStringVar(&options.Vars, "set", nil, "set a variable of the config, e.g. --set OCP_VERSION=4.18 (can be repeated)")
+	command.Flags().StringVar(&options.MatrixPath, "matrix", defaultMatrixPath(), "path to the compatibility matrix providing {{ .Matrix }} in the config")
+}
+
+// configExpander expands the variables and templates of the values of a config.
+type configExpander struct {
+	vars       map[string]string // the variables of the config, the environment and --set, by precedence
+	options    ConfigOptions
+	matrix     *MatrixEntry
+	matrixRead bool
+}
+
+// newConfigExpander returns an expander of the given vars block: a variable set with --set wins over the environment,
+// which wins over the vars block of the config, both for ${VAR} and .Vars.
+func newConfigExpander(vars map[string]string, options ConfigOptions) *configExpander {
+	merged := map[string]string{}
+	for name, value := range vars {
+		merged[name] = value
+	}
+	for _, variable := range os.Environ() {
+		if name, value, found := strings.Cut(variable, "="); found {
+			merged[name] = value
+		}
+	}
+	for name, value := range options.Vars {
+		merged[name] = value
+	}
+	return &configExpander{vars: merged, options: options}
+}
+
+// lookup returns the value of a variable.
+func (e *configExpander) lookup(name string) (string, bool) {
+	value, found := e.vars[name]
+	return value, found
+}
+
+// expandVariables replaces each ${VAR} of the value by the value of the variable.
+func (e *configExpander) expandVariables(value string) (string, error) {
+	var undefined []string
+	expanded := variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		variable, found := e.lookup(name)
+		if !found {
+			undefined = append(undefined, name)
+		}
+		return variable
+	})
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined variable %s", strings.Join(undefined, ", "))
+	}
+	return expanded, nil
+}
+
+// expand replaces the variables of the value, then executes it as a template with the variables as .Vars and the
+// compatibility matrix entry of the OpenShift version in OCP_VERSION as .Matrix.
+func (e *configExpander) expand(value string) (string, error) {
+	value, err := e.expandVariables(value)
+	if err != nil || !strings.Contains(value, "{{") {
+		return value, err
+	}
+
+	tmpl, err := template.New("config").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		Vars   map[string]string
+		Matrix *MatrixEntry
+	}{Vars: e.vars}
+	if strings.Contains(value, ".Matrix") {
+		if data.Matrix, err = e.matrixEntry(); err != nil {
+			return "", err
+		}
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// matrixEntry returns the compatibility matrix entry of the OpenShift version in OCP_VERSION, read once.
+func (e *configExpander) matrixEntry() (*MatrixEntry, error) {
+	if e.matrixRead {
+		return e.matrix, nil
+	}
+
+	version, found := e.lookup(matrixVersionVar)
+	if !found {
+		return nil, fmt.Errorf("{{ .Matrix }} requires the %s variable", matrixVersionVar)
+	}
+	matrix, err := loadMatrix(e.options.MatrixPath)
+	if err != nil {
+		return nil, err
+	}
+	entry, found := matrix.entry(version)
+	if !found {
+		return nil, missingMatrixEntryError(e.options.MatrixPath, version)
+	}
+
+	e.matrix, e.matrixRead = entry, true
+	return entry, nil
+}
+
+// expandConfig expands the variables and templates of the go section and the dependencies of the config.
+func expandConfig(config *Config, options ConfigOptions) error {
+	expander := newConfigExpander(config.Vars, options)
+
+	type field struct {
+		where string
+		value *string
+	}
+	var fields []field
+	if config.Go != nil {
+		fields = append(fields,
+			field{"go: maxVersion", &config.Go.MaxVersion},
+			field{"go: toolchain", &config.Go.Toolchain})
+	}
+	for i := range config.Dependencies {
+		dep := &config.Dependencies[i]
+		where := fmt.Sprintf("dependency %s", dep.Package)
+		fields = append(fields,
+			field{where, &dep.Package},
+			field{where, &dep.Version},
+			field{where, &dep.Branch},
+			field{where, &dep.Group},
+			field{where, &dep.SourceBranch})
+	}
+
+	for _, f := range fields {
+		expanded, err := expander.expand(*f.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.where, err)
+		}
+		*f.value = expanded
+	}
+
+	return nil
+}
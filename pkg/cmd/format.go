@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// configFormat returns the format of the config at the given location: the given format if any,
+// otherwise the one matching its extension, YAML by default.
+func configFormat(location string, format ConfigFormat) (ConfigFormat, error) {
+	if format != "" {
+		return format, validateConfigFormat(format)
+	}
+
+	switch strings.ToLower(path.Ext(location)) {
+	case ".json":
+		return ConfigFormatJSON, nil
+	case ".toml":
+		return ConfigFormatTOML, nil
+	default:
+		return ConfigFormatYAML, nil
+	}
+}
+
+func validateConfigFormat(format ConfigFormat) error {
+	switch format {
+	case ConfigFormatYAML, ConfigFormatJSON, ConfigFormatTOML:
+		return nil
+	default:
+		return fmt.Errorf("unknown config format %q: must be one of %s, %s, %s", format, ConfigFormatYAML, ConfigFormatJSON, ConfigFormatTOML)
+	}
+}
+
+// decodeConfig parses the config read from the given location in the given format, refusing unknown fields so that
+// typos don't go unnoticed.
+func decodeConfig(data []byte, location string, format ConfigFormat) (*Config, error) {
+	var config Config
+	switch format {
+	case ConfigFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("%s: %s", location, jsonErrorMessage(data, err))
+		}
+	case ConfigFormatTOML:
+		metadata, err := toml.Decode(string(data), &config)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+		var errs []error
+		for _, key := range metadata.Undecoded() {
+			if line := tomlKeyLine(data, key); line > 0 {
+				errs = append(errs, fmt.Errorf("%s: line %d: unknown field %q", location, line, key.String()))
+			} else {
+				errs = append(errs, fmt.Errorf("%s: unknown field %q", location, key.String()))
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+	default:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.SetStrict(true)
+		if err := decoder.Decode(&config); err != nil {
+			return nil, configDecodeError(location, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// jsonErrorMessage describes an error of decoding a JSON config, with the line of syntax errors and unknown fields.
+func jsonErrorMessage(data []byte, err error) string {
+	msg := strings.TrimPrefix(err.Error(), "json: ")
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("line %d: %s", lineOfOffset(data, syntaxErr.Offset), msg)
+	}
+	if field, found := strings.CutPrefix(msg, "unknown field "); found {
+		if name, err := strconv.Unquote(field); err == nil {
+			if line := jsonKeyLine(data, name); line > 0 {
+				return fmt.Sprintf("line %d: %s", line, msg)
+			}
+		}
+	}
+	return msg
+}
+
+// jsonKeyLine returns the line of the first object key of a JSON document with the given name, or 0 if there is none.
+// The decoder reports unknown fields without their offset, so the document is walked token by token to find it.
+func jsonKeyLine(data []byte, name string) int {
+	type container struct {
+		object    bool
+		expectKey bool
+	}
+	var stack []container
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0
+		}
+		var top *container
+		if len(stack) > 0 {
+			top = &stack[len(stack)-1]
+		}
+		switch token {
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if key, ok := token.(string); ok && top != nil && top.object && top.expectKey {
+			if key == name {
+				return lineOfOffset(data, decoder.InputOffset())
+			}
+			top.expectKey = false
+			continue
+		}
+		// any other token is a value, ending the key/value pair of the object it is in
+		if top != nil && top.object {
+			top.expectKey = true
+		}
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, container{object: true, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, container{})
+		}
+	}
+}
+
+// tomlKeyLine returns the line defining the given key of a TOML document, or 0 if it isn't found. The toml package
+// keeps the positions of keys to itself, so the table headers and key/value pairs of the document are scanned instead.
+func tomlKeyLine(data []byte, key toml.Key) int {
+	var table toml.Key
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			header, _, _ := strings.Cut(strings.TrimLeft(line, "["), "]")
+			table = tomlDottedKey(header)
+			if slices.Equal(table, key) {
+				return i + 1
+			}
+		default:
+			name, _, found := strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			// a key holding an inline table also defines the keys inside it
+			defined := append(slices.Clone(table), tomlDottedKey(name)...)
+			if len(defined) <= len(key) && slices.Equal(defined, key[:len(defined)]) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// tomlDottedKey splits a TOML key such as a."b.c".d into its parts, unquoted.
+func tomlDottedKey(s string) toml.Key {
+	var key toml.Key
+	var part strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			part.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			key = append(key, strings.TrimSpace(part.String()))
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(key, strings.TrimSpace(part.String()))
+}
+
+// lineOfOffset returns the line of the given byte offset of data, starting at 1.
+func lineOfOffset(data []byte, offset int64) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// encodeConfig marshals the config in the given format.
+func encodeConfig(cfg *Config, format ConfigFormat) ([]byte, error) {
+	var data []byte
+	var err error
+	switch format {
+	case ConfigFormatJSON:
+		if data, err = json.MarshalIndent(cfg, "", "  "); err == nil {
+			data = append(data, '\n')
+		}
+	case ConfigFormatTOML:
+		var out bytes.Buffer
+		encoder := toml.NewEncoder(&out)
+		encoder.Indent = ""
+		err = encoder.Encode(cfg)
+		data = out.Bytes()
+	default:
+		data, err = yaml.Marshal(cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
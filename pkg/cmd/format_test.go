@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var formatTestConfig = &Config{
+	Include: []string{"../base.yaml"},
+	Vars:    map[string]string{"OCP_VERSION": "4.18"},
+	Go:      &GoConfig{Apply: true, Toolchain: "go1.23.4", MaxVersion: "1.23"},
+	Exclude: []string{"github.com/onsi/*"},
+	SkipVersions: map[string][]string{
+		"sigs.k8s.io/controller-runtime": {"v0.20.0", "v0.20.1"},
+	},
+	Dependencies: []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+		{Package: "github.com/openshift/api", Branch: "release-${OCP_VERSION}"},
+		{Package: "github.com/openshift/library-go", Version: "v0.0.0-20250301100000-0123456789ab", SourceBranch: "release-4.18"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+	},
+}
+
+func TestConfigFormatRoundTrip(t *testing.T) {
+	for _, format := range []ConfigFormat{ConfigFormatYAML, ConfigFormatJSON, ConfigFormatTOML} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := encodeConfig(formatTestConfig, format)
+			require.NoError(t, err)
+
+			decoded, err := decodeConfig(data, "config", format)
+
+			require.NoError(t, err)
+			assert.Equal(t, formatTestConfig, decoded)
+		})
+	}
+}
+
+func TestParseConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]string{
+		"config.yaml": `dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "k8s.io/api"
+    version: "v0.31.1"
+    group: kubernetes
+`,
+		"config.json": `{
+  "dependencies": [
+    {"package": "sigs.k8s.io/controller-runtime", "version": "v0.19.3"},
+    {"package": "k8s.io/api", "version": "v0.31.1", "group": "kubernetes"}
+  ]
+}
+`,
+		"config.toml": `[[dependencies]]
+package = "sigs.k8s.io/controller-runtime"
+version = "v0.19.3"
+
+[[dependencies]]
+package = "k8s.io/api"
+version = "v0.31.1"
+group = "kubernetes"
+`,
+	}
+	expected := []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+	}
+	for name, content := range configs {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(configPath, []byte(content), 0600))
+
+			config, err := parseConfig(configPath, ConfigOptions{})
+
+			require.NoError(t, err)
+			assert.Equal(t, expected, config.Dependencies)
+		})
+	}
+
+	t.Run("format flag", func(t *testing.T) {
+		configPath := filepath.Join(dir, "config")
+		require.NoError(t, os.WriteFile(configPath, []byte(configs["config.json"]), 0600))
+
+		config, err := parseConfig(configPath, ConfigOptions{Format: ConfigFormatJSON})
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, config.Dependencies)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := parseConfig(filepath.Join(dir, "config.json"), ConfigOptions{Format: "xml"})
+		require.EqualError(t, err, `unknown config format "xml": must be one of yaml, json, toml`)
+	})
+
+	t.Run("include of another format", func(t *testing.T) {
+		configPath := filepath.Join(dir, "including.toml")
+		require.NoError(t, os.WriteFile(configPath, []byte("include = [\"config.json\"]\n\n[[dependencies]]\npackage = \"k8s.io/api\"\nversion = \"v0.31.2\"\n"), 0600))
+
+		config, err := parseConfig(configPath, ConfigOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{
+			{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+			{Package: "k8s.io/api", Version: "v0.31.2"},
+		}, config.Dependencies)
+	})
+}
+
+func TestDecodeConfigUnknownFields(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		_, err := decodeConfig([]byte(`{
+  "dependencies": [
+    {"package": "sigs.k8s.io/controller-runtime", "version": "v0.19.3"},
+    {
+      "package": "k8s.io/api",
+      "verison": "v0.31.1"
+    }
+  ]
+}
+`), "config.json", ConfigFormatJSON)
+		require.EqualError(t, err, `config.json: line 6: unknown field "verison"`)
+	})
+
+	t.Run("json syntax error", func(t *testing.T) {
+		_, err := decodeConfig([]byte("{\n  \"dependencies\": [\n    {\"package\": \"k8s.io/api\",}\n  ]\n}\n"), "config.json", ConfigFormatJSON)
+		require.EqualError(t, err, `config.json: line 3: invalid character '}' looking for beginning of object key string`)
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		_, err := decodeConfig([]byte(`# upgraded weekly
+[[dependencies]]
+package = "sigs.k8s.io/controller-runtime"
+version = "v0.19.3"
+
+[[dependencies]]
+package = "k8s.io/api"
+verison = "v0.31.1"
+`), "config.toml", ConfigFormatTOML)
+		require.EqualError(t, err, `config.toml: line 8: unknown field "dependencies.verison"`)
+	})
+
+	t.Run("toml table and inline table", func(t *testing.T) {
+		_, err := decodeConfig([]byte(`[go]
+apply = true
+toolchian = { version = "go1.23.4" }
+
+[proxy]
+url = "https://proxy.golang.org"
+`), "config.toml", ConfigFormatTOML)
+		require.EqualError(t, err, `config.toml: line 3: unknown field "go.toolchian"
+config.toml: line 3: unknown field "go.toolchian.version"
+config.toml: line 5: unknown field "proxy"
+config.toml: line 6: unknown field "proxy.url"`)
+	})
+}
+
+func TestWriteGeneratedConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	generated := &Config{Dependencies: []Dependency{{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"}}}
+
+	t.Run("json", func(t *testing.T) {
+		configPath := filepath.Join(dir, "config.json")
+
+		require.NoError(t, writeGeneratedConfig(generated, configPath, GenerateOptions{}))
+
+		content, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		assert.Equal(t, `{
+  "dependencies": [
+    {
+      "package": "k8s.io/api",
+      "version": "v0.31.1",
+      "group": "kubernetes"
+    }
+  ]
+}
+`, string(content))
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		configPath := filepath.Join(dir, "deps")
+
+		require.NoError(t, writeGeneratedConfig(generated, configPath, GenerateOptions{Format: ConfigFormatTOML}))
+
+		config, err := parseConfig(configPath, ConfigOptions{Format: ConfigFormatTOML})
+		require.NoError(t, err)
+		assert.Equal(t, generated.Dependencies, config.Dependencies)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		configPath := filepath.Join(dir, "merge.json")
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"go": {"maxVersion": "1.23"}, "dependencies": [
+  {"package": "github.com/onsi/ginkgo/v2", "version": "v2.19.0"},
+  {"package": "k8s.io/api", "version": "v0.30.1"}
+]}`), 0600))
+
+		require.NoError(t, writeGeneratedConfig(&Config{Dependencies: []Dependency{
+			{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+			{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+		}}, configPath, GenerateOptions{Merge: true}))
+
+		config, err := parseConfig(configPath, ConfigOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, &Config{
+			Go: &GoConfig{MaxVersion: "1.23"},
+			Dependencies: []Dependency{
+				{Package: "github.com/onsi/ginkgo/v2", Version: "v2.19.0"},
+				{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+				{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+			},
+		}, config)
+	})
+}
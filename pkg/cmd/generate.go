@@ -3,17 +3,22 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/rsoaresd/goupgrader/pkg/cmd/flags"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
 )
 
 func NewGenerateConfigForOpenshiftDependencies() *cobra.Command {
-	var targetOpenshiftVersion, currentOperatorSdkVersion, outputPath string
+	var targetOpenshiftVersion, targetKubernetesVersion, targetKubebuilderVersion, currentOperatorSdkVersion, outputPath string
+	var fromProject, project string
+	var packages []string
+	var options GenerateOptions
 
 	command := &cobra.Command{
 		Use:   "generate --target-openshift-version=<target-openshift-version> --in-use-op-sdk-version=<in-use-op-sdk-version> --output=<path-to-save-config>",
@@ -21,100 +26,166 @@ func NewGenerateConfigForOpenshiftDependencies() *cobra.Command {
 		Long: `This command analyzes the Kubernetes version used by a specific OpenShift release and compares it to the 
 dependencies used by various operator-sdk versions. Once a compatible operator-sdk version is found (with matching 
 Kubernetes minor version), it fetches related dependency versions and generates a YAML configuration file. 
-This config can be used to align your Go project dependencies with the target OpenShift release.`,
+This config can be used to align your Go project dependencies with the target OpenShift release.
+
+Instead of an OpenShift release, --target-kubernetes-version and --target-kubebuilder-version match the
+operator-sdk release with a Kubernetes version directly or with the Kubernetes version of the project
+scaffolded by a kubebuilder release. The OpenShift branch-tracked dependencies are left out in these modes.
+
+Alternatively, with --from-project, the config aligns dependencies with the versions required by the go.mod
+of any reference project, either local or <repo>@<ref> on GitHub.`,
 		Args: cobra.ExactArgs(0),
 		RunE: func(_ *cobra.Command, _ []string) error {
-			return GenerateConfigForOpenshiftDependencies(targetOpenshiftVersion, currentOperatorSdkVersion, outputPath)
+			if options.Merge && outputPath == stdioPath {
+				return fmt.Errorf("--merge cannot be used when writing the config to stdout")
+			}
+			if fromProject != "" {
+				return GenerateConfigFromProject(fromProject, packages, project, outputPath, options)
+			}
+			if currentOperatorSdkVersion == "" && options.OperatorSdkVersion == "" && !options.Offline {
+				return fmt.Errorf("required flag(s) \"in-use-op-sdk-version\" not set")
+			}
+			switch {
+			case targetKubernetesVersion != "":
+				return GenerateConfigForKubernetesVersion(targetKubernetesVersion, currentOperatorSdkVersion, outputPath, options)
+			case targetKubebuilderVersion != "":
+				return GenerateConfigForKubebuilderVersion(targetKubebuilderVersion, currentOperatorSdkVersion, outputPath, options)
+			}
+			return GenerateConfigForOpenshiftDependencies(targetOpenshiftVersion, currentOperatorSdkVersion, outputPath, options)
 		},
 	}
 	command.Flags().StringVarP(&targetOpenshiftVersion, "target-openshift-version", "t", "", "openshift version you wish to upgrade dependencies")
-	flags.MustMarkRequired(command, "target-openshift-version")
+	command.Flags().StringVar(&targetKubernetesVersion, "target-kubernetes-version", "", "Kubernetes version you wish to upgrade dependencies to, e.g. 1.31, instead of an Openshift version")
+	command.Flags().StringVar(&targetKubebuilderVersion, "target-kubebuilder-version", "", "kubebuilder release whose scaffolded project's Kubernetes version you wish to upgrade dependencies to, e.g. v4.3.0")
 	command.Flags().StringVarP(&currentOperatorSdkVersion, "in-use-op-sdk-version", "i", "", "current operator-sdk version in your Go project")
-	flags.MustMarkRequired(command, "in-use-op-sdk-version")
-	command.Flags().StringVarP(&outputPath, "output", "o", "", "path to  save the YAML config with the dependencies list for the target Openshift version")
+	command.Flags().StringVarP(&outputPath, "output", "o", "", "path to  save the YAML config with the dependencies list for the target Openshift version, or - to write it to stdout")
 	flags.MustMarkRequired(command, "output")
+	command.Flags().StringVar(&fromProject, "from-project", "", "reference project to align dependencies with: a local path or <repo>[/<dir>]@<ref> on GitHub")
+	command.Flags().StringSliceVar(&packages, "packages", nil, "packages to align with the reference project (defaults to all dependencies shared with --project)")
+	command.Flags().StringVarP(&project, "project", "p", "", "path to your Go project, to align all dependencies it shares with the reference project")
+
+	command.Flags().BoolVar(&options.IncludePrereleases, "include-prereleases", false, "also consider operator-sdk prereleases (e.g. release candidates)")
+	command.Flags().StringVar((*string)(&options.MatchPolicy), "match-policy", string(MatchHighest), "how to choose between the operator-sdk releases matching the target Kubernetes version: highest, closest (to --in-use-op-sdk-version) or exact-k8s-patch")
+	command.Flags().StringVar(&options.OperatorSdkVersion, "op-sdk-version", "", "operator-sdk release to derive the dependency versions from, instead of searching for a match")
+	command.Flags().StringVar(&options.ProfilePath, "profile", "", "YAML profile listing the packages to include and whether each is version- or branch-tracked (defaults to the built-in profile)")
+
+	command.Flags().StringVar(&options.Distribution, "distribution", DistributionOCP, "OpenShift distribution of --target-openshift-version: ocp, okd (e.g. 4.17.0-okd-scos.0) or microshift (e.g. 4.17.1)")
+	command.Flags().StringVar((*string)(&options.Format), "format", "", "format of the --output config: yaml, json or toml (defaults to the extension of --output, or yaml)")
+	command.Flags().BoolVar(&options.Merge, "merge", false, "merge the generated dependencies into the existing --output config, keeping its other entries and comments")
+	command.Flags().BoolVar(&options.Pin, "pin", false, "pin the branch-tracked dependencies to the pseudo-version of the latest commit of their branch, for reproducible configs")
+	command.Flags().BoolVar(&options.Offline, "offline", false, "read the dependency versions from the compatibility matrix instead of upstream (see 'goupgrader matrix refresh')")
+	command.Flags().StringVar(&options.MatrixPath, "matrix", defaultMatrixPath(), "compatibility matrix file used with --offline")
+
+	command.MarkFlagsOneRequired("target-openshift-version", "target-kubernetes-version", "target-kubebuilder-version", "from-project")
+	command.MarkFlagsMutuallyExclusive("target-openshift-version", "target-kubernetes-version", "target-kubebuilder-version", "from-project")
+	command.MarkFlagsMutuallyExclusive("distribution", "target-kubernetes-version", "target-kubebuilder-version", "from-project")
+	command.MarkFlagsMutuallyExclusive("offline", "target-kubernetes-version")
+	command.MarkFlagsMutuallyExclusive("offline", "target-kubebuilder-version")
+	command.MarkFlagsMutuallyExclusive("offline", "from-project")
+	command.MarkFlagsMutuallyExclusive("offline", "pin")
+	command.MarkFlagsMutuallyExclusive("offline", "op-sdk-version")
 
 	return command
 }
 
-func GenerateConfigForOpenshiftDependencies(openshiftVersion, currentOperatorSdkVersion, configPath string) error {
-	// find which k8s version Openshift is using
-	k8sVersionUsedByOpenshift, err := getKubernetesVersionUsedByOpenshift(openshiftVersion)
+func GenerateConfigForOpenshiftDependencies(openshiftVersion, currentOperatorSdkVersion, configPath string, options GenerateOptions) error {
+	if err := validateMatchPolicy(options.MatchPolicy); err != nil {
+		return err
+	}
+
+	profile, err := loadProfile(options.ProfilePath)
 	if err != nil {
 		return err
 	}
-	log.Info().Msgf("k8s version used by Openshift %s: %s", openshiftVersion, k8sVersionUsedByOpenshift)
 
-	// find which operator sdk version is using the target k8s version and build the config
-	cfg, err := findMatchingOperatorSDKConfig(k8sVersionUsedByOpenshift, currentOperatorSdkVersion, openshiftVersion)
+	distribution, err := lookupDistribution(options.Distribution)
+	if err != nil {
+		return err
+	}
+	openshiftVersion, err = distribution.releaseVersion(openshiftVersion)
 	if err != nil {
 		return err
 	}
 
-	return saveConfigToFile(cfg, configPath)
-}
+	if options.Offline {
+		if distribution.Repository != distributions[DistributionOCP].Repository {
+			return fmt.Errorf("--offline cannot be used with the %s distribution: the compatibility matrix is built from the %s release branches",
+				distribution.Name, distributions[DistributionOCP].Repository)
+		}
+		cfg, err := generateConfigFromMatrix(openshiftVersion, profile, options.MatrixPath)
+		if err != nil {
+			return err
+		}
+		return writeGeneratedConfig(cfg, configPath, options)
+	}
 
-func generateVersions(startMajorS, startMinorS string) ([]string, error) {
-	minorCount := 4 // how many minors to include (e.g. 38, 39, 40, 41)
-	patchCount := 5 // how many patch versions per minor
-	var versions []string
+	// find which k8s version Openshift is using
+	k8sVersionUsedByOpenshift, err := distribution.kubernetesVersion(openshiftVersion)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("k8s version used by %s %s: %s", distribution.Name, openshiftVersion, k8sVersionUsedByOpenshift)
 
-	startMajor, err := strconv.Atoi(startMajorS)
+	// find which operator sdk version is using the target k8s version and build the config
+	cfg, err := findMatchingOperatorSDKConfig(k8sVersionUsedByOpenshift, currentOperatorSdkVersion, openshiftVersion, profile, options)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	startMinor, err := strconv.Atoi(startMinorS)
+	// align the k8s.io modules themselves with the ones used by Openshift
+	k8sDependencies, err := distribution.kubernetesDependencies(openshiftVersion)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	cfg.Dependencies = appendMissingDependencies(cfg.Dependencies, k8sDependencies)
 
-	for i := 0; i < minorCount; i++ {
-		currentMinor := startMinor + i
-		for p := 0; p <= patchCount; p++ {
-			version := fmt.Sprintf("v%d.%d.%d", startMajor, currentMinor, p)
-			versions = append(versions, version)
+	if options.Pin {
+		if err := pinBranchDependencies(cfg); err != nil {
+			return err
 		}
 	}
 
-	// reverse to be in desc order
-	return reverseSlice(versions), nil
+	return writeGeneratedConfig(cfg, configPath, options)
 }
 
-func reverseSlice(s []string) []string {
-	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
-		s[i], s[j] = s[j], s[i]
-	}
-	return s
-}
-
-func hasSameMinorVersion(v1, v2 string) (bool, error) {
-	v1 = strings.TrimPrefix(v1, "v")
-	v2 = strings.TrimPrefix(v2, "v")
+// pinBranchDependencies replaces the branch of the branch-tracked dependencies with the pseudo-version of the
+// latest commit of the branch, recording the branch as the source of the version.
+func pinBranchDependencies(cfg *Config) error {
+	for i, dep := range cfg.Dependencies {
+		if dep.Branch == "" {
+			continue
+		}
 
-	v1Parts := strings.Split(v1, ".")
-	v2Parts := strings.Split(v2, ".")
+		version, err := branchVersionFunc(dep.Package, dep.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to pin dependency %s to branch %s: %w", dep.Package, dep.Branch, err)
+		}
+		log.Info().Msgf("pinned %s branch %s to %s", dep.Package, dep.Branch, version)
 
-	if len(v1Parts) < 2 || len(v2Parts) < 2 {
-		return false, fmt.Errorf("invalid version format")
+		cfg.Dependencies[i].Version = version
+		cfg.Dependencies[i].SourceBranch = dep.Branch
+		cfg.Dependencies[i].Branch = ""
 	}
 
-	major1, err1 := strconv.Atoi(v1Parts[0])
-	minor1, err2 := strconv.Atoi(v1Parts[1])
-	major2, err3 := strconv.Atoi(v2Parts[0])
-	minor2, err4 := strconv.Atoi(v2Parts[1])
-
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		return false, fmt.Errorf("invalid version numbers")
-	}
+	return nil
+}
 
-	return major1 == major2 && minor1 == minor2, nil
+// compareMinorVersions compares the major.minor part of two versions, with or without the "v" prefix,
+// and returns -1, 0 or +1 like semver.Compare.
+func compareMinorVersions(v1, v2 string) int {
+	return semver.Compare(semver.MajorMinor("v"+strings.TrimPrefix(v1, "v")), semver.MajorMinor("v"+strings.TrimPrefix(v2, "v")))
 }
 
-func saveConfigToFile(cfg *Config, filename string) error {
-	data, err := yaml.Marshal(&cfg)
+// saveConfigToFile writes the config to the given file, or to stdout if the file is "-".
+func saveConfigToFile(cfg *Config, filename string, format ConfigFormat) error {
+	data, err := encodeConfig(cfg, format)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
+	}
+
+	if filename == stdioPath {
+		_, err := stdout.Write(data)
+		return err
 	}
 
 	err = os.WriteFile(filename, data, 0600)
@@ -131,75 +202,339 @@ func getKubernetesVersionUsedByOpenshift(openshiftVersion string) (string, error
 	return GetKubernetesVersion("openshift/api", fmt.Sprintf("release-%s", openshiftVersion), "k8s.io/api")
 }
 
-func generateCandidateSdkVersions(currentVersion string) ([]string, error) {
-	currentVersion = strings.TrimPrefix(currentVersion, "v")
-	parts := strings.Split(currentVersion, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid operator-sdk version format")
-	}
-	return generateVersions(parts[0], parts[1])
+// kubernetesGroup is the dependency group of the k8s.io modules in generated configs
+const kubernetesGroup = "kubernetes"
+
+// getKubernetesDependenciesUsedByOpenshift returns all the k8s.io/* modules required by the openshift/api release
+// branch of the given Openshift version, as dependencies of the kubernetes group.
+func getKubernetesDependenciesUsedByOpenshift(openshiftVersion string) ([]Dependency, error) {
+	return getKubernetesDependenciesFromBranch("openshift/api", fmt.Sprintf("release-%s", openshiftVersion))
 }
 
-func buildDependencyConfig(repo, branch, openshiftVersion string) (Config, error) {
-	config := Config{}
+// getKubernetesDependenciesFromBranch returns all the k8s.io/* modules required by the go.mod of the given
+// GitHub repository branch, as dependencies of the kubernetes group.
+func getKubernetesDependenciesFromBranch(repo, branch string) ([]Dependency, error) {
+	goMod, err := fetchGoMod(repo, branch)
+	if err != nil {
+		return nil, err
+	}
 
-	dependencies := []string{
-		"sigs.k8s.io/controller-runtime",
-		"github.com/operator-framework/api",
-		"github.com/operator-framework/operator-registry",
-		"sigs.k8s.io/controller-tools",
+	modFile, err := modfile.ParseLax(repo+"@"+branch+"/go.mod", goMod, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod of %s@%s: %w", repo, branch, err)
 	}
 
+	return kubernetesDependencies(modFile), nil
+}
+
+// kubernetesDependencies returns the k8s.io/* modules required by the go.mod file, as dependencies of the kubernetes group.
+func kubernetesDependencies(modFile *modfile.File) []Dependency {
+	var dependencies []Dependency
+	for _, req := range modFile.Require {
+		if strings.HasPrefix(req.Mod.Path, "k8s.io/") {
+			dependencies = append(dependencies, Dependency{
+				Package: req.Mod.Path,
+				Version: req.Mod.Version,
+				Group:   kubernetesGroup,
+			})
+		}
+	}
+
+	return dependencies
+}
+
+// appendMissingDependencies appends the additional dependencies whose package is not already in dependencies.
+func appendMissingDependencies(dependencies, additional []Dependency) []Dependency {
+	existing := map[string]bool{}
 	for _, dep := range dependencies {
-		version, err := GetKubernetesVersion(repo, branch, dep)
-		if err != nil {
-			return Config{}, fmt.Errorf("failed to get version for %s: %w", dep, err)
+		existing[dep.Package] = true
+	}
+
+	for _, dep := range additional {
+		if !existing[dep.Package] {
+			dependencies = append(dependencies, dep)
 		}
-		config.Dependencies = append(config.Dependencies, Dependency{
-			Package: dep,
-			Version: version,
-		})
 	}
 
-	openshiftPackages := []string{
-		"github.com/openshift/api",
-		"github.com/openshift/library-go",
+	return dependencies
+}
+
+// operatorSdkModule is the module path of the operator-sdk
+const operatorSdkModule = "github.com/operator-framework/operator-sdk"
+
+// listOperatorSdkReleases returns the operator-sdk releases published on the module proxy, in descending order.
+// Prereleases (e.g. v1.40.0-rc.1) are only included if includePrereleases is true.
+func listOperatorSdkReleases(includePrereleases bool) ([]string, error) {
+	versions, err := listModuleVersions(operatorSdkModule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operator-sdk releases: %w", err)
 	}
 
-	for _, opPkg := range openshiftPackages {
-		config.Dependencies = append(config.Dependencies, Dependency{
-			Package: opPkg,
-			Branch:  fmt.Sprintf("release-%s", openshiftVersion),
-		})
+	var releases []string
+	for _, version := range versions {
+		if !semver.IsValid(version) || (!includePrereleases && semver.Prerelease(version) != "") {
+			continue
+		}
+		releases = append(releases, version)
 	}
 
-	return config, nil
+	sort.Slice(releases, func(i, j int) bool {
+		return semver.Compare(releases[i], releases[j]) > 0
+	})
+
+	return releases, nil
 }
 
-func findMatchingOperatorSDKConfig(k8sVersionUsedByOpenshift, currentOperatorSdkVersion, openshiftVersion string) (*Config, error) {
-	sdkVersions, err := generateCandidateSdkVersions(currentOperatorSdkVersion)
+// generateCandidateSdkVersions returns the operator-sdk releases to search for the target Kubernetes version,
+// in descending order. If the operator-sdk version in use already uses a newer Kubernetes minor version than the
+// target one, the search goes backwards from it; otherwise it goes forward from its minor version.
+func generateCandidateSdkVersions(currentVersion, k8sVersion string, includePrereleases bool) ([]string, error) {
+	currentVersion = "v" + strings.TrimPrefix(currentVersion, "v")
+	if semver.Canonical(currentVersion) != currentVersion {
+		return nil, fmt.Errorf("invalid operator-sdk version format")
+	}
+
+	releases, err := listOperatorSdkReleases(includePrereleases)
 	if err != nil {
 		return nil, err
 	}
 
+	k8sVersionUsedByCurrent, err := GetKubernetesVersion("operator-framework/operator-sdk", currentVersion, "k8s.io/api")
+	if err != nil {
+		log.Info().Msgf("cannot check the Kubernetes version used by operator-sdk %s (%v): searching all releases", currentVersion, err)
+		return releases, nil
+	}
+
+	var candidates []string
+	if compareMinorVersions(k8sVersionUsedByCurrent, k8sVersion) > 0 {
+		log.Info().Msgf("operator-sdk %s uses Kubernetes %s, newer than %s: searching older releases",
+			currentVersion, k8sVersionUsedByCurrent, k8sVersion)
+		for _, release := range releases {
+			if semver.Compare(release, currentVersion) <= 0 {
+				candidates = append(candidates, release)
+			}
+		}
+		return candidates, nil
+	}
+
+	start := semver.MajorMinor(currentVersion) + ".0"
+	for _, release := range releases {
+		if semver.Compare(release, start) >= 0 {
+			candidates = append(candidates, release)
+		}
+	}
+
+	return candidates, nil
+}
+
+// explainKubernetesGap returns the error reported when none of the candidates uses the Kubernetes minor version
+// of k8sVersion, explaining which Kubernetes versions the closest operator-sdk releases use.
+func explainKubernetesGap(candidates []OperatorSdkCandidate, k8sVersion string) error {
+	var newer, older *OperatorSdkCandidate
+	for i, candidate := range candidates {
+		// candidates are in descending order: keep the lowest newer one and the highest older one
+		if compareMinorVersions(candidate.KubernetesVersion, k8sVersion) > 0 {
+			newer = &candidates[i]
+		} else if older == nil {
+			older = &candidates[i]
+		}
+	}
+
+	var reason string
+	switch {
+	case newer != nil && older != nil:
+		reason = fmt.Sprintf("operator-sdk %s uses Kubernetes %s and the next release, %s, already uses Kubernetes %s",
+			older.Version, older.KubernetesVersion, newer.Version, newer.KubernetesVersion)
+	case newer != nil:
+		reason = fmt.Sprintf("the oldest operator-sdk release, %s, already uses Kubernetes %s", newer.Version, newer.KubernetesVersion)
+	case older != nil:
+		reason = fmt.Sprintf("the newest operator-sdk release, %s, still uses Kubernetes %s", older.Version, older.KubernetesVersion)
+	default:
+		reason = "no operator-sdk release could be checked"
+	}
+
+	return fmt.Errorf("no matching operator-sdk version found for Kubernetes %s: %s", k8sVersion, reason)
+}
+
+func buildDependencyConfig(repo, branch, openshiftVersion string, profile *Profile) (Config, error) {
+	return buildProfileConfig(profile, openshiftVersion, func(pkg string) (string, error) {
+		return GetKubernetesVersion(repo, branch, pkg)
+	})
+}
+
+// buildProfileConfig builds the config of the profile dependencies, looking up the versions of the
+// version-tracked ones and tracking the release branch of the Openshift version for the branch-tracked ones.
+func buildProfileConfig(profile *Profile, openshiftVersion string, lookupVersion func(pkg string) (string, error)) (Config, error) {
+	config := Config{}
+
+	for _, dep := range profile.Dependencies {
+		switch dep.Track {
+		case TrackVersion:
+			version, err := lookupVersion(dep.Package)
+			if err != nil {
+				return Config{}, fmt.Errorf("failed to get version for %s: %w", dep.Package, err)
+			}
+			config.Dependencies = append(config.Dependencies, Dependency{
+				Package: dep.Package,
+				Version: version,
+			})
+		case TrackBranch:
+			config.Dependencies = append(config.Dependencies, Dependency{
+				Package: dep.Package,
+				Branch:  fmt.Sprintf("release-%s", openshiftVersion),
+			})
+		}
+	}
+
+	return config, nil
+}
+
+func findMatchingOperatorSDKConfig(k8sVersionUsedByOpenshift, currentOperatorSdkVersion, openshiftVersion string, profile *Profile, options GenerateOptions) (*Config, error) {
+	version := options.OperatorSdkVersion
+	if version == "" {
+		sdkVersions, err := generateCandidateSdkVersions(currentOperatorSdkVersion, k8sVersionUsedByOpenshift, options.IncludePrereleases)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates := collectOperatorSdkCandidates(sdkVersions, k8sVersionUsedByOpenshift)
+		logCandidateMatrix(candidates, k8sVersionUsedByOpenshift)
+
+		version, err = selectOperatorSdkVersion(candidates, k8sVersionUsedByOpenshift, currentOperatorSdkVersion, options.MatchPolicy)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Info().Msgf("using operator-sdk %s as requested", version)
+	}
+
+	config, err := buildDependencyConfig("operator-framework/operator-sdk", version, openshiftVersion, profile)
+	if err != nil {
+		return nil, fmt.Errorf("error building config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// collectOperatorSdkCandidates looks up the Kubernetes version used by each of the given operator-sdk releases,
+// sorted in descending order, until the releases use an older Kubernetes minor version than the target one.
+func collectOperatorSdkCandidates(sdkVersions []string, k8sVersion string) []OperatorSdkCandidate {
+	var candidates []OperatorSdkCandidate
+
 	for _, version := range sdkVersions {
 		k8sVersionUsedBySdk, err := GetKubernetesVersion("operator-framework/operator-sdk", version, "k8s.io/api")
 		if err != nil {
 			log.Info().Msgf("skipping SDK version %s: %v", version, err)
 			continue
 		}
+		candidates = append(candidates, OperatorSdkCandidate{Version: version, KubernetesVersion: k8sVersionUsedBySdk})
+
+		// releases are in descending order, so the remaining ones use even older Kubernetes versions
+		if compareMinorVersions(k8sVersionUsedBySdk, k8sVersion) < 0 {
+			break
+		}
+	}
 
-		if same, _ := hasSameMinorVersion(k8sVersionUsedBySdk, k8sVersionUsedByOpenshift); same {
-			log.Info().Msgf("match found! SDK %s uses Kubernetes %s", version, k8sVersionUsedBySdk)
+	return candidates
+}
 
-			config, err := buildDependencyConfig("operator-framework/operator-sdk", version, openshiftVersion)
-			if err != nil {
-				return nil, fmt.Errorf("error building config: %w", err)
+// logCandidateMatrix prints which Kubernetes version each operator-sdk candidate uses.
+func logCandidateMatrix(candidates []OperatorSdkCandidate, k8sVersion string) {
+	for _, candidate := range candidates {
+		match := ""
+		if compareMinorVersions(candidate.KubernetesVersion, k8sVersion) == 0 {
+			match = " (match)"
+		}
+		log.Info().Msgf("operator-sdk %s -> Kubernetes %s%s", candidate.Version, candidate.KubernetesVersion, match)
+	}
+}
+
+// selectOperatorSdkVersion chooses, according to the policy, the operator-sdk release among the candidates
+// that uses the same Kubernetes minor version as k8sVersion.
+func selectOperatorSdkVersion(candidates []OperatorSdkCandidate, k8sVersion, currentOperatorSdkVersion string, policy MatchPolicy) (string, error) {
+	var matches []OperatorSdkCandidate
+	for _, candidate := range candidates {
+		if compareMinorVersions(candidate.KubernetesVersion, k8sVersion) == 0 {
+			matches = append(matches, candidate)
+		}
+	}
+
+	if len(matches) == 0 {
+		return "", explainKubernetesGap(candidates, k8sVersion)
+	}
+
+	// highest first
+	sort.Slice(matches, func(i, j int) bool {
+		return semver.Compare(matches[i].Version, matches[j].Version) > 0
+	})
+
+	if err := validateMatchPolicy(policy); err != nil {
+		return "", err
+	}
+
+	selected := matches[0]
+	switch policy {
+	case MatchHighest, "":
+	case MatchClosest:
+		// matches are sorted, so the highest patch release of the closest minor version wins
+		current := "v" + strings.TrimPrefix(currentOperatorSdkVersion, "v")
+		for _, match := range matches[1:] {
+			if minorDistance(match.Version, current) < minorDistance(selected.Version, current) {
+				selected = match
+			}
+		}
+	case MatchExactKubernetesPatch:
+		exact := false
+		for _, match := range matches {
+			if semver.Compare(match.KubernetesVersion, k8sVersion) == 0 {
+				selected, exact = match, true
+				break
 			}
+		}
+		if !exact {
+			log.Warn().Msgf("no operator-sdk release uses exactly Kubernetes %s: falling back to the highest match", k8sVersion)
+		}
+	}
+
+	log.Info().Msgf("match found! SDK %s uses Kubernetes %s", selected.Version, selected.KubernetesVersion)
+
+	return selected.Version, nil
+}
 
-			return &config, nil
+// validateMatchPolicy checks if the match policy is known.
+func validateMatchPolicy(policy MatchPolicy) error {
+	switch policy {
+	case MatchHighest, MatchClosest, MatchExactKubernetesPatch, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown match policy %q: must be one of %s, %s, %s", policy, MatchHighest, MatchClosest, MatchExactKubernetesPatch)
+	}
+}
+
+// minorDistance returns how many minor versions apart two versions are, weighting major versions over minor versions.
+func minorDistance(v1, v2 string) int {
+	distance := func(a, b int) int {
+		if a > b {
+			return a - b
 		}
+		return b - a
 	}
 
-	return nil, fmt.Errorf("no matching operator-sdk version found for Kubernetes %s", k8sVersionUsedByOpenshift)
+	major1, minor1 := versionNumbers(v1)
+	major2, minor2 := versionNumbers(v2)
+
+	return distance(major1, major2)*1_000 + distance(minor1, minor2)
+}
+
+// versionNumbers returns the major and minor numbers of a semantic version.
+func versionNumbers(version string) (int, int) {
+	parts := strings.Split(strings.TrimPrefix(semver.MajorMinor(version), "v"), ".")
+	if len(parts) != 2 {
+		return 0, 0
+	}
+
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+
+	return major, minor
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/modfile"
+)
+
+// GenerateConfigFromProject generates a config aligning dependencies with the versions required by a reference project.
+// The reference is either a local path (to a directory or to a go.mod file) or <repo>[/<dir>]@<ref> for a GitHub
+// repository, e.g. github.com/kubernetes-sigs/kubebuilder/testdata/project-v4@v4.3.0.
+// If packages are given, the config aligns those packages; otherwise it aligns every dependency the reference
+// shares with the Go project at projectPath.
+func GenerateConfigFromProject(reference string, packages []string, projectPath, configPath string, options GenerateOptions) error {
+	referenceMod, err := readReferenceGoMod(reference)
+	if err != nil {
+		return err
+	}
+
+	referenceVersions := map[string]string{}
+	for _, req := range referenceMod.Require {
+		referenceVersions[req.Mod.Path] = req.Mod.Version
+	}
+
+	if len(packages) == 0 {
+		if projectPath == "" {
+			return fmt.Errorf("either --packages or --project must be provided with --from-project")
+		}
+		if packages, err = sharedDependencies(projectPath, referenceVersions); err != nil {
+			return err
+		}
+	}
+
+	cfg := &Config{}
+	for _, pkg := range packages {
+		version, found := referenceVersions[pkg]
+		if !found {
+			return fmt.Errorf("%s is not required by %s", pkg, reference)
+		}
+		cfg.Dependencies = append(cfg.Dependencies, Dependency{
+			Package: pkg,
+			Version: version,
+		})
+	}
+	log.Info().Msgf("aligning %d dependencies with %s", len(cfg.Dependencies), reference)
+
+	return writeGeneratedConfig(cfg, configPath, options)
+}
+
+// sharedDependencies returns the dependencies of the project, in go.mod order, that are also in the given versions.
+func sharedDependencies(projectPath string, versions map[string]string) ([]string, error) {
+	module, err := readModule(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	for _, pkg := range module.Require {
+		if _, found := versions[pkg.Path]; found {
+			shared = append(shared, pkg.Path)
+		}
+	}
+
+	return shared, nil
+}
+
+// readReferenceGoMod reads the go.mod file of a local or remote reference project.
+func readReferenceGoMod(reference string) (*modfile.File, error) {
+	var data []byte
+
+	if info, err := os.Stat(reference); err == nil {
+		path := reference
+		if info.IsDir() {
+			path = filepath.Join(reference, "go.mod")
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read go.mod of %s: %w", reference, err)
+		}
+	} else {
+		repo, dir, ref, err := parseRemoteReference(reference)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = fetchGitHubFile(repo, ref, strings.TrimPrefix(dir+"/go.mod", "/")); err != nil {
+			return nil, fmt.Errorf("failed to fetch go.mod of %s: %w", reference, err)
+		}
+	}
+
+	modFile, err := modfile.ParseLax(reference, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod of %s: %w", reference, err)
+	}
+
+	return modFile, nil
+}
+
+// parseRemoteReference splits [github.com/]<owner>/<name>[/<dir>]@<ref> into the repository, directory and ref.
+func parseRemoteReference(reference string) (string, string, string, error) {
+	path, ref, found := strings.Cut(reference, "@")
+	if !found || ref == "" {
+		return "", "", "", fmt.Errorf("invalid reference project %q: must be a local path or <repo>@<ref>", reference)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(path, "github.com/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid reference project %q: repository must be [github.com/]<owner>/<name>", reference)
+	}
+
+	var dir string
+	if len(parts) == 3 {
+		dir = strings.Trim(parts[2], "/")
+	}
+
+	return parts[0] + "/" + parts[1], dir, ref, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const referenceGoMod = `module example.com/reference
+
+go 1.22.0
+
+require (
+	github.com/onsi/ginkgo/v2 v2.19.0
+	k8s.io/client-go v0.31.0
+	sigs.k8s.io/controller-runtime v0.19.0
+)
+
+require github.com/go-logr/logr v1.4.2 // indirect
+`
+
+// newFakeGitHubRawContent starts a server serving the given files, keyed by "<owner>/<name>/<ref>/<path>".
+func newFakeGitHubRawContent(t *testing.T, files map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, found := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	t.Cleanup(server.Close)
+
+	origGitHubRawContentURL := githubRawContentURL
+	githubRawContentURL = server.URL
+	t.Cleanup(func() { githubRawContentURL = origGitHubRawContentURL })
+}
+
+func TestGenerateConfigFromProject(t *testing.T) {
+	newFakeGitHubRawContent(t, map[string]string{
+		"kubernetes-sigs/kubebuilder/v4.3.0/testdata/project-v4/go.mod": referenceGoMod,
+		"example/platform/main/go.mod":                                  referenceGoMod,
+	})
+
+	localReference := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localReference, "go.mod"), []byte(referenceGoMod), 0600))
+
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"},{"Path":"github.com/spf13/cobra","Version":"v1.8.1"},{"Path":"github.com/go-logr/logr","Version":"v1.4.1"}]}`}
+	}
+
+	tests := []struct {
+		name            string
+		args            []string
+		expectedError   string
+		expectedContent string
+	}{
+		{
+			name: "remote reference with directory and listed packages",
+			args: []string{
+				"--from-project=github.com/kubernetes-sigs/kubebuilder/testdata/project-v4@v4.3.0",
+				"--packages=k8s.io/client-go,github.com/onsi/ginkgo/v2",
+			},
+			expectedContent: `dependencies:
+- package: k8s.io/client-go
+  version: v0.31.0
+- package: github.com/onsi/ginkgo/v2
+  version: v2.19.0
+`,
+		},
+		{
+			name: "remote reference with shared dependencies",
+			args: []string{
+				"--from-project=example/platform@main",
+				"--project=/path/to/project",
+			},
+			expectedContent: `dependencies:
+- package: sigs.k8s.io/controller-runtime
+  version: v0.19.0
+- package: github.com/go-logr/logr
+  version: v1.4.2
+`,
+		},
+		{
+			name: "local reference",
+			args: []string{
+				fmt.Sprintf("--from-project=%s", localReference),
+				"--packages=sigs.k8s.io/controller-runtime",
+			},
+			expectedContent: `dependencies:
+- package: sigs.k8s.io/controller-runtime
+  version: v0.19.0
+`,
+		},
+		{
+			name: "package not required by the reference",
+			args: []string{
+				fmt.Sprintf("--from-project=%s", localReference),
+				"--packages=github.com/openshift/api",
+			},
+			expectedError: fmt.Sprintf("github.com/openshift/api is not required by %s", localReference),
+		},
+		{
+			name:          "neither packages nor project",
+			args:          []string{"--from-project=example/platform@main"},
+			expectedError: "either --packages or --project must be provided with --from-project",
+		},
+		{
+			name:          "unknown ref",
+			args:          []string{"--from-project=example/platform@release-1.0", "--packages=k8s.io/client-go"},
+			expectedError: "failed to fetch go.mod of example/platform@release-1.0: non 200 response: 404",
+		},
+		{
+			name:          "missing ref",
+			args:          []string{"--from-project=example/platform", "--packages=k8s.io/client-go"},
+			expectedError: "invalid reference project \"example/platform\": must be a local path or <repo>@<ref>",
+		},
+		{
+			name:          "mutually exclusive with target openshift version",
+			args:          []string{"--from-project=example/platform@main", "--target-openshift-version=4.18"},
+			expectedError: "if any flags in the group [target-openshift-version target-kubernetes-version target-kubebuilder-version from-project] are set none of the others can be; [from-project target-openshift-version] were all set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "config.yaml")
+
+			cmd := NewGenerateConfigForOpenshiftDependencies()
+			cmd.SetArgs(append(test.args, fmt.Sprintf("--output=%s", output)))
+
+			err := cmd.Execute()
+
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			content, err := os.ReadFile(output)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedContent, string(content))
+		})
+	}
+}
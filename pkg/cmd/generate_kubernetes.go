@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/semver"
+)
+
+// kubebuilderScaffold is the reference to the go.mod of the project scaffolded by kubebuilder, without the version
+const kubebuilderScaffold = "github.com/kubernetes-sigs/kubebuilder/testdata/project-v4"
+
+// GenerateConfigForKubernetesVersion generates a config with the dependencies of the operator-sdk release matching
+// the given Kubernetes version, e.g. 1.31 or 1.31.2.
+func GenerateConfigForKubernetesVersion(kubernetesVersion, currentOperatorSdkVersion, configPath string, options GenerateOptions) error {
+	k8sVersion, err := kubernetesModuleVersion(kubernetesVersion)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("k8s.io modules version of Kubernetes %s: %s", kubernetesVersion, k8sVersion)
+
+	return generateConfigForKubernetes(k8sVersion, nil, currentOperatorSdkVersion, configPath, options)
+}
+
+// GenerateConfigForKubebuilderVersion generates a config with the dependencies of the operator-sdk release matching
+// the Kubernetes version of the project scaffolded by the given kubebuilder release, e.g. v4.3.0, and with the
+// k8s.io modules of the scaffolded project.
+func GenerateConfigForKubebuilderVersion(kubebuilderVersion, currentOperatorSdkVersion, configPath string, options GenerateOptions) error {
+	modFile, err := readReferenceGoMod(fmt.Sprintf("%s@%s", kubebuilderScaffold, kubebuilderVersion))
+	if err != nil {
+		return err
+	}
+
+	k8sDependencies := kubernetesDependencies(modFile)
+	k8sVersion := ""
+	for _, dep := range k8sDependencies {
+		if dep.Package == "k8s.io/api" || (k8sVersion == "" && dep.Package == "k8s.io/apimachinery") {
+			k8sVersion = dep.Version
+		}
+	}
+	if k8sVersion == "" {
+		return fmt.Errorf("the project scaffolded by kubebuilder %s requires neither k8s.io/api nor k8s.io/apimachinery", kubebuilderVersion)
+	}
+	log.Info().Msgf("k8s version used by kubebuilder %s: %s", kubebuilderVersion, k8sVersion)
+
+	return generateConfigForKubernetes(k8sVersion, k8sDependencies, currentOperatorSdkVersion, configPath, options)
+}
+
+// generateConfigForKubernetes saves the config of the profile dependencies, at the versions used by the operator-sdk
+// release matching the Kubernetes version, followed by the given k8s.io modules. Branch-tracked dependencies
+// follow Openshift release branches, so they are left out.
+func generateConfigForKubernetes(k8sVersion string, k8sDependencies []Dependency, currentOperatorSdkVersion, configPath string, options GenerateOptions) error {
+	if err := validateMatchPolicy(options.MatchPolicy); err != nil {
+		return err
+	}
+
+	profile, err := loadProfile(options.ProfilePath)
+	if err != nil {
+		return err
+	}
+
+	versionTracked := &Profile{}
+	for _, dep := range profile.Dependencies {
+		if dep.Track == TrackBranch {
+			log.Info().Msgf("skipping %s: branch-tracked dependencies follow Openshift release branches", dep.Package)
+			continue
+		}
+		versionTracked.Dependencies = append(versionTracked.Dependencies, dep)
+	}
+
+	cfg, err := findMatchingOperatorSDKConfig(k8sVersion, currentOperatorSdkVersion, "", versionTracked, options)
+	if err != nil {
+		return err
+	}
+	cfg.Dependencies = appendMissingDependencies(cfg.Dependencies, k8sDependencies)
+
+	return writeGeneratedConfig(cfg, configPath, options)
+}
+
+// kubernetesModuleVersion converts a Kubernetes version, e.g. 1.31 or v1.31.2, to the version of the
+// matching k8s.io modules, e.g. v0.31.0 or v0.31.2.
+func kubernetesModuleVersion(kubernetesVersion string) (string, error) {
+	version := "v" + strings.TrimPrefix(kubernetesVersion, "v")
+	if strings.Count(version, ".") == 0 || !semver.IsValid(version) || semver.Prerelease(version) != "" || semver.Build(version) != "" || semver.Major(version) != "v1" {
+		return "", fmt.Errorf("invalid Kubernetes version %q: must be 1.<minor>[.<patch>]", kubernetesVersion)
+	}
+
+	return "v0" + strings.TrimPrefix(semver.Canonical(version), "v1"), nil
+}
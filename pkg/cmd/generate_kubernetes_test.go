@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kubebuilderScaffoldGoMod = `module tutorial.kubebuilder.io/project
+
+go 1.22.0
+
+require (
+	k8s.io/apimachinery v0.31.0
+	k8s.io/client-go v0.31.0
+	sigs.k8s.io/controller-runtime v0.19.0
+)
+
+require k8s.io/api v0.31.0 // indirect
+`
+
+func TestGenerateConfigForKubernetesTargets(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"github.com/operator-framework/operator-sdk/@v/list": "v1.38.0\nv1.39.1\nv1.40.0\n",
+	})
+	newFakeGitHubRawContent(t, map[string]string{
+		"kubernetes-sigs/kubebuilder/v4.2.0/testdata/project-v4/go.mod": kubebuilderScaffoldGoMod,
+		"operator-framework/operator-sdk/v1.38.0/go.mod":                fullOperatorSdkGoMod("v0.30.3", "v0.18.5"),
+		"operator-framework/operator-sdk/v1.39.1/go.mod":                fullOperatorSdkGoMod("v0.31.2", "v0.19.4"),
+		"operator-framework/operator-sdk/v1.40.0/go.mod":                fullOperatorSdkGoMod("v0.32.1", "v0.20.4"),
+	})
+	expectedDependencies := `dependencies:
+- package: sigs.k8s.io/controller-runtime
+  version: v0.19.4
+- package: github.com/operator-framework/api
+  version: v0.27.0
+- package: github.com/operator-framework/operator-registry
+  version: v1.49.0
+- package: sigs.k8s.io/controller-tools
+  version: v0.16.5
+`
+
+	t.Run("Kubernetes version", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "config.yaml")
+		cmd := NewGenerateConfigForOpenshiftDependencies()
+		cmd.SetArgs([]string{"--target-kubernetes-version=1.31", "--in-use-op-sdk-version=v1.38.0", "--output=" + output})
+
+		err := cmd.Execute()
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDependencies, string(content))
+	})
+
+	t.Run("kubebuilder version", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "config.yaml")
+		cmd := NewGenerateConfigForOpenshiftDependencies()
+		cmd.SetArgs([]string{"--target-kubebuilder-version=v4.2.0", "--in-use-op-sdk-version=v1.38.0", "--output=" + output})
+
+		err := cmd.Execute()
+
+		require.NoError(t, err)
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDependencies+`- package: k8s.io/apimachinery
+  version: v0.31.0
+  group: kubernetes
+- package: k8s.io/client-go
+  version: v0.31.0
+  group: kubernetes
+- package: k8s.io/api
+  version: v0.31.0
+  group: kubernetes
+`, string(content))
+	})
+
+	t.Run("unknown kubebuilder version", func(t *testing.T) {
+		err := GenerateConfigForKubebuilderVersion("v4.9.9", "v1.38.0", filepath.Join(t.TempDir(), "config.yaml"), GenerateOptions{})
+		require.EqualError(t, err, "failed to fetch go.mod of github.com/kubernetes-sigs/kubebuilder/testdata/project-v4@v4.9.9: non 200 response: 404")
+	})
+
+	t.Run("offline is not supported", func(t *testing.T) {
+		cmd := NewGenerateConfigForOpenshiftDependencies()
+		cmd.SetArgs([]string{"--target-kubernetes-version=1.31", "--offline", "--output=" + filepath.Join(t.TempDir(), "config.yaml")})
+
+		err := cmd.Execute()
+
+		require.EqualError(t, err, "if any flags in the group [offline target-kubernetes-version] are set none of the others can be; [offline target-kubernetes-version] were all set")
+	})
+}
+
+func TestKubernetesModuleVersion(t *testing.T) {
+	for kubernetesVersion, expected := range map[string]string{
+		"1.31":    "v0.31.0",
+		"v1.31":   "v0.31.0",
+		"1.30.4":  "v0.30.4",
+		"v1.32.1": "v0.32.1",
+	} {
+		version, err := kubernetesModuleVersion(kubernetesVersion)
+		require.NoError(t, err)
+		assert.Equal(t, expected, version, kubernetesVersion)
+	}
+
+	for _, kubernetesVersion := range []string{"1", "0.31", "2.1", "1.31.0-rc.1", "latest"} {
+		_, err := kubernetesModuleVersion(kubernetesVersion)
+		require.EqualError(t, err, `invalid Kubernetes version "`+kubernetesVersion+`": must be 1.<minor>[.<patch>]`)
+	}
+}
@@ -3,10 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestGenerateConfigForOpenshiftDependencies(t *testing.T) {
@@ -45,7 +47,11 @@ func TestGenerateConfigForOpenshiftDependencies(t *testing.T) {
 - package: github.com/openshift/library-go
   branch: release-4.18
 `
-		assert.Equal(t, expectedContent, string(fileContent))
+		// followed by the k8s.io modules used by openshift/api, whose versions move with the release branch
+		require.True(t, strings.HasPrefix(string(fileContent), expectedContent))
+		assert.Contains(t, string(fileContent), `- package: k8s.io/api
+  version: v0.31.`)
+		assert.Contains(t, string(fileContent), "  group: kubernetes\n")
 	})
 
 	testCases := []struct {
@@ -70,7 +76,7 @@ func TestGenerateConfigForOpenshiftDependencies(t *testing.T) {
 				"--in-use-op-sdk-version=vvvv1.39.0",
 				"--output=/tmp/invalid.yaml",
 			},
-			expectedError: "strconv.Atoi: parsing \"vvv1\": invalid syntax",
+			expectedError: "invalid operator-sdk version format",
 		},
 		{
 			name: "invalid SDK version format",
@@ -99,3 +105,281 @@ func TestGenerateConfigForOpenshiftDependencies(t *testing.T) {
 		})
 	}
 }
+
+func TestGetKubernetesDependenciesUsedByOpenshift(t *testing.T) {
+	newFakeGitHubRawContent(t, map[string]string{
+		"openshift/api/release-4.18/go.mod": `module github.com/openshift/api
+
+go 1.22.0
+
+require (
+	github.com/spf13/cobra v1.8.1
+	k8s.io/api v0.31.1
+	k8s.io/apimachinery v0.31.1
+	k8s.io/code-generator v0.31.1
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	k8s.io/klog/v2 v2.130.1 // indirect
+)
+`,
+	})
+
+	dependencies, err := getKubernetesDependenciesUsedByOpenshift("4.18")
+
+	require.NoError(t, err)
+	assert.Equal(t, []Dependency{
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+		{Package: "k8s.io/apimachinery", Version: "v0.31.1", Group: "kubernetes"},
+		{Package: "k8s.io/code-generator", Version: "v0.31.1", Group: "kubernetes"},
+		{Package: "k8s.io/klog/v2", Version: "v2.130.1", Group: "kubernetes"},
+	}, dependencies)
+
+	t.Run("unknown openshift version", func(t *testing.T) {
+		_, err := getKubernetesDependenciesUsedByOpenshift("4.99")
+		require.EqualError(t, err, "non 200 response: 404")
+	})
+}
+
+func TestAppendMissingDependencies(t *testing.T) {
+	dependencies := appendMissingDependencies(
+		[]Dependency{{Package: "k8s.io/client-go", Version: "v0.31.2"}},
+		[]Dependency{
+			{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+			{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+		})
+
+	assert.Equal(t, []Dependency{
+		{Package: "k8s.io/client-go", Version: "v0.31.2"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+	}, dependencies)
+}
+
+func TestGenerateCandidateSdkVersions(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"github.com/operator-framework/operator-sdk/@v/list": "v1.38.0\nv1.39.0\nv1.39.1\nv1.40.0-rc.1\nv1.37.0\nv1.39.10\nv1.40.0\n",
+	})
+	newFakeGitHubRawContent(t, map[string]string{
+		"operator-framework/operator-sdk/v1.39.1/go.mod": operatorSdkGoMod("v0.31.2", "v0.19.4"),
+		"operator-framework/operator-sdk/v1.39.0/go.mod": operatorSdkGoMod("v0.31.0", "v0.19.0"),
+	})
+
+	t.Run("releases from the current minor version in descending order", func(t *testing.T) {
+		versions, err := generateCandidateSdkVersions("v1.39.1", "v0.32.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.40.0", "v1.39.10", "v1.39.1", "v1.39.0"}, versions)
+	})
+
+	t.Run("including prereleases", func(t *testing.T) {
+		versions, err := generateCandidateSdkVersions("1.39.0", "v0.31.1", true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.40.0", "v1.40.0-rc.1", "v1.39.10", "v1.39.1", "v1.39.0"}, versions)
+	})
+
+	t.Run("older releases when the target Kubernetes version is older", func(t *testing.T) {
+		versions, err := generateCandidateSdkVersions("v1.39.1", "v0.30.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.39.1", "v1.39.0", "v1.38.0", "v1.37.0"}, versions)
+	})
+
+	t.Run("all releases when the current version cannot be checked", func(t *testing.T) {
+		versions, err := generateCandidateSdkVersions("v1.38.0", "v0.30.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.40.0", "v1.39.10", "v1.39.1", "v1.39.0", "v1.38.0", "v1.37.0"}, versions)
+	})
+
+	t.Run("invalid version format", func(t *testing.T) {
+		_, err := generateCandidateSdkVersions("v1.39", "v0.31.1", false)
+		require.EqualError(t, err, "invalid operator-sdk version format")
+	})
+}
+
+// operatorSdkGoMod returns a minimal go.mod of an operator-sdk release using the given versions
+func operatorSdkGoMod(k8sVersion, controllerRuntimeVersion string) string {
+	return fmt.Sprintf(`module github.com/operator-framework/operator-sdk
+
+require (
+	k8s.io/api %s
+	sigs.k8s.io/controller-runtime %s
+)
+`, k8sVersion, controllerRuntimeVersion)
+}
+
+func TestFindMatchingOperatorSDKConfig(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"github.com/operator-framework/operator-sdk/@v/list": "v1.37.0\nv1.38.0\nv1.39.0\nv1.39.1\nv1.40.0\n",
+	})
+	newFakeGitHubRawContent(t, map[string]string{
+		"operator-framework/operator-sdk/v1.37.0/go.mod": operatorSdkGoMod("v0.30.1", "v0.18.4"),
+		"operator-framework/operator-sdk/v1.38.0/go.mod": operatorSdkGoMod("v0.30.3", "v0.18.5"),
+		"operator-framework/operator-sdk/v1.39.0/go.mod": operatorSdkGoMod("v0.31.0", "v0.19.0"),
+		"operator-framework/operator-sdk/v1.39.1/go.mod": operatorSdkGoMod("v0.31.2", "v0.19.4"),
+		"operator-framework/operator-sdk/v1.40.0/go.mod": operatorSdkGoMod("v0.32.1", "v0.20.4"),
+	})
+	profile := &Profile{Dependencies: []ProfileDependency{{Package: "sigs.k8s.io/controller-runtime", Track: TrackVersion}}}
+
+	t.Run("highest release using the same Kubernetes minor version", func(t *testing.T) {
+		config, err := findMatchingOperatorSDKConfig("v0.31.1", "v1.37.0", "4.18", profile, GenerateOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"}}, config.Dependencies)
+	})
+
+	t.Run("older release than the current version", func(t *testing.T) {
+		config, err := findMatchingOperatorSDKConfig("v0.30.2", "v1.40.0", "4.17", profile, GenerateOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{{Package: "sigs.k8s.io/controller-runtime", Version: "v0.18.5"}}, config.Dependencies)
+	})
+
+	t.Run("no matching release", func(t *testing.T) {
+		_, err := findMatchingOperatorSDKConfig("v0.33.0", "v1.37.0", "4.20", profile, GenerateOptions{})
+		require.EqualError(t, err, "no matching operator-sdk version found for Kubernetes v0.33.0: "+
+			"the newest operator-sdk release, v1.40.0, still uses Kubernetes v0.32.1")
+	})
+
+	t.Run("no older matching release", func(t *testing.T) {
+		_, err := findMatchingOperatorSDKConfig("v0.29.0", "v1.40.0", "4.16", profile, GenerateOptions{})
+		require.EqualError(t, err, "no matching operator-sdk version found for Kubernetes v0.29.0: "+
+			"the oldest operator-sdk release, v1.37.0, already uses Kubernetes v0.30.1")
+	})
+
+	t.Run("forced operator-sdk release", func(t *testing.T) {
+		config, err := findMatchingOperatorSDKConfig("v0.31.1", "", "4.18", profile, GenerateOptions{OperatorSdkVersion: "v1.39.0"})
+		require.NoError(t, err)
+		assert.Equal(t, []Dependency{{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.0"}}, config.Dependencies)
+	})
+}
+
+func TestSelectOperatorSdkVersion(t *testing.T) {
+	candidates := []OperatorSdkCandidate{
+		{Version: "v1.41.0", KubernetesVersion: "v0.32.1"},
+		{Version: "v1.40.2", KubernetesVersion: "v0.31.3"},
+		{Version: "v1.40.1", KubernetesVersion: "v0.31.2"},
+		{Version: "v1.39.2", KubernetesVersion: "v0.31.2"},
+		{Version: "v1.39.0", KubernetesVersion: "v0.31.0"},
+		{Version: "v1.38.0", KubernetesVersion: "v0.30.1"},
+	}
+
+	tests := []struct {
+		name            string
+		k8sVersion      string
+		currentVersion  string
+		policy          MatchPolicy
+		expectedVersion string
+		expectedError   string
+	}{
+		{
+			name:            "highest",
+			k8sVersion:      "v0.31.2",
+			currentVersion:  "v1.38.0",
+			policy:          MatchHighest,
+			expectedVersion: "v1.40.2",
+		},
+		{
+			name:            "closest to the current version",
+			k8sVersion:      "v0.31.2",
+			currentVersion:  "v1.38.0",
+			policy:          MatchClosest,
+			expectedVersion: "v1.39.2",
+		},
+		{
+			name:            "exact Kubernetes patch version",
+			k8sVersion:      "v0.31.2",
+			currentVersion:  "v1.38.0",
+			policy:          MatchExactKubernetesPatch,
+			expectedVersion: "v1.40.1",
+		},
+		{
+			name:            "exact Kubernetes patch version falls back to highest",
+			k8sVersion:      "v0.31.1",
+			currentVersion:  "v1.38.0",
+			policy:          MatchExactKubernetesPatch,
+			expectedVersion: "v1.40.2",
+		},
+		{
+			name:           "no match",
+			k8sVersion:     "v0.29.0",
+			currentVersion: "v1.38.0",
+			policy:         MatchHighest,
+			expectedError: "no matching operator-sdk version found for Kubernetes v0.29.0: " +
+				"the oldest operator-sdk release, v1.38.0, already uses Kubernetes v0.30.1",
+		},
+		{
+			name:           "unknown policy",
+			k8sVersion:     "v0.31.2",
+			currentVersion: "v1.38.0",
+			policy:         "lowest",
+			expectedError:  "unknown match policy \"lowest\": must be one of highest, closest, exact-k8s-patch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			version, err := selectOperatorSdkVersion(candidates, test.k8sVersion, test.currentVersion, test.policy)
+
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedVersion, version)
+		})
+	}
+}
+
+func TestExplainKubernetesGap(t *testing.T) {
+	candidates := []OperatorSdkCandidate{
+		{Version: "v1.32.0", KubernetesVersion: "v0.28.1"},
+		{Version: "v1.31.0", KubernetesVersion: "v0.28.0"},
+		{Version: "v1.30.0", KubernetesVersion: "v0.26.2"},
+	}
+
+	err := explainKubernetesGap(candidates, "v0.27.4")
+
+	require.EqualError(t, err, "no matching operator-sdk version found for Kubernetes v0.27.4: "+
+		"operator-sdk v1.30.0 uses Kubernetes v0.26.2 and the next release, v1.31.0, already uses Kubernetes v0.28.0")
+
+	t.Run("no candidates", func(t *testing.T) {
+		err := explainKubernetesGap(nil, "v0.27.4")
+		require.EqualError(t, err, "no matching operator-sdk version found for Kubernetes v0.27.4: no operator-sdk release could be checked")
+	})
+}
+
+func TestPinBranchDependencies(t *testing.T) {
+	origBranchVersionFunc := branchVersionFunc
+	t.Cleanup(func() { branchVersionFunc = origBranchVersionFunc })
+	var resolved []string
+	branchVersionFunc = func(repo, branch string) (string, error) {
+		resolved = append(resolved, repo+"@"+branch)
+		if repo == "github.com/openshift/unknown" {
+			return "", fmt.Errorf("no commit found for branch %s", branch)
+		}
+		return "v0.0.0-20250301100000-0123456789ab", nil
+	}
+
+	cfg := &Config{Dependencies: []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+		{Package: "github.com/openshift/api", Branch: "release-4.18"},
+	}}
+
+	err := pinBranchDependencies(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"github.com/openshift/api@release-4.18"}, resolved)
+	assert.Equal(t, []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+		{Package: "github.com/openshift/api", Version: "v0.0.0-20250301100000-0123456789ab", SourceBranch: "release-4.18"},
+	}, cfg.Dependencies)
+
+	data, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `- package: github.com/openshift/api
+  version: v0.0.0-20250301100000-0123456789ab
+  sourceBranch: release-4.18
+`)
+
+	t.Run("unknown branch", func(t *testing.T) {
+		err := pinBranchDependencies(&Config{Dependencies: []Dependency{{Package: "github.com/openshift/unknown", Branch: "release-4.18"}}})
+		require.EqualError(t, err, "failed to pin dependency github.com/openshift/unknown to branch release-4.18: no commit found for branch release-4.18")
+	})
+}
@@ -6,10 +6,21 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/rs/zerolog/log"
 )
 
+var gitCommandFunc = func(projectPath string, arg ...string) commandExecutor {
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = projectPath
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
+
 // getVersionWithCommitHashForBranch fetches the latest commit hash for the given branch in version format
 func getVersionWithCommitHashForBranch(repo, branch string) (string, error) {
 	repoURL := fmt.Sprintf("https://%s.git", repo)
@@ -56,3 +67,149 @@ func getVersionWithCommitHashForBranch(repo, branch string) (string, error) {
 
 	return version, nil
 }
+
+// branchVersionFunc resolves a branch to the pseudo-version of its latest commit, replaceable in tests
+var branchVersionFunc = getVersionWithCommitHashForBranch
+
+// ensureCleanWorkingTree returns an error if the git working tree containing the project has uncommitted changes.
+func ensureCleanWorkingTree(projectPath string) error {
+	output, err := gitCommandFunc(projectPath, "status", "--porcelain").Output()
+	if err != nil {
+		return fmt.Errorf("failed to run 'git status': %w", err)
+	}
+
+	if strings.TrimSpace(string(output)) != "" {
+		return fmt.Errorf("working tree of %s is not clean: commit or stash your changes first", projectPath)
+	}
+
+	return nil
+}
+
+// createBranch creates and checks out a new git branch in the project. When a pull request is opened, a branch left
+// by a previous run, i.e. tracking the branch of the same name on the remote the pull request is pushed to, is reset
+// instead, so that the pull request is updated. Any other existing branch is left alone and the upgrade fails.
+func createBranch(projectPath, branch, remote string) error {
+	if remote != "" {
+		upstream, err := branchUpstream(projectPath, branch)
+		if err != nil {
+			return err
+		}
+		if upstream == remote+"/"+branch {
+			log.Info().Msgf("resetting git branch %s of a previous run...", branch)
+			if err := gitCommandFunc(projectPath, "checkout", "-B", branch).Run(); err != nil {
+				return fmt.Errorf("error resetting git branch %s: %w", branch, err)
+			}
+
+			return nil
+		}
+	}
+
+	log.Info().Msgf("creating git branch %s...", branch)
+	if err := gitCommandFunc(projectPath, "checkout", "-b", branch).Run(); err != nil {
+		return fmt.Errorf("error creating git branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// branchUpstream returns the upstream (e.g. origin/name) of the given local branch of the project, or an empty string
+// if the branch doesn't exist or has no upstream.
+func branchUpstream(projectPath, branch string) (string, error) {
+	output, err := gitCommandFunc(projectPath, "for-each-ref", "--format=%(upstream:short)", "refs/heads/"+branch).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get upstream of git branch %s: %w", branch, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// commitChanges stages every change in the project directory and commits it with the given message.
+// The working tree is known to be clean before the upgrade starts, so all changes come from goupgrader.
+func commitChanges(projectPath, message string) error {
+	if err := gitCommandFunc(projectPath, "add", "--all", ".").Run(); err != nil {
+		return fmt.Errorf("error staging changes: %w", err)
+	}
+
+	if err := gitCommandFunc(projectPath, "commit", "--quiet", "--message", message).Run(); err != nil {
+		return fmt.Errorf("error committing changes: %w", err)
+	}
+
+	log.Info().Msgf("committed: %s", strings.SplitN(message, "\n", 2)[0])
+
+	return nil
+}
+
+// commitMessage generates a commit message listing the upgraded and added dependencies.
+func commitMessage(results []UpgradeResult) string {
+	var upgraded []UpgradeResult
+	for _, result := range results {
+		if result.Status == UpgradeStatusUpgraded || result.Status == UpgradeStatusAdded {
+			upgraded = append(upgraded, result)
+		}
+	}
+
+	var message strings.Builder
+	switch {
+	case len(upgraded) == 1 && upgraded[0].Status == UpgradeStatusAdded:
+		fmt.Fprintf(&message, "Add %s %s\n\n", upgraded[0].Package, upgraded[0].To)
+	case len(upgraded) == 1:
+		fmt.Fprintf(&message, "Upgrade %s to %s\n\n", upgraded[0].Package, upgraded[0].To)
+	default:
+		fmt.Fprintf(&message, "Upgrade %d Go dependencies\n\n", len(upgraded))
+	}
+
+	for _, result := range upgraded {
+		if result.Status == UpgradeStatusAdded {
+			fmt.Fprintf(&message, "- %s: added at %s\n", result.Package, result.To)
+			continue
+		}
+		fmt.Fprintf(&message, "- %s: %s → %s\n", result.Package, result.From, result.To)
+	}
+
+	return message.String()
+}
+
+// currentBranch returns the name of the branch checked out in the project.
+func currentBranch(projectPath string) (string, error) {
+	output, err := gitCommandFunc(projectPath, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current git branch: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// pushBranch pushes the given branch of the project to the remote and sets it as upstream. As the branch of a previous
+// run is recreated rather than updated, the push is forced, unless the remote branch changed since it was last fetched.
+func pushBranch(projectPath, remote, branch string) error {
+	log.Info().Msgf("pushing git branch %s to %s...", branch, remote)
+	if err := gitCommandFunc(projectPath, "push", "--force-with-lease", "--set-upstream", remote, branch).Run(); err != nil {
+		return fmt.Errorf("error pushing git branch %s to %s: %w", branch, remote, err)
+	}
+
+	return nil
+}
+
+// repositoryFromRemote returns the repository path (e.g. owner/name) of the given remote of the project,
+// supporting both HTTPS (https://host/owner/name.git) and SSH (git@host:owner/name.git) remote URLs.
+func repositoryFromRemote(projectPath, remote string) (string, error) {
+	output, err := gitCommandFunc(projectPath, "remote", "get-url", remote).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get URL of git remote %s: %w", remote, err)
+	}
+	remoteURL := strings.TrimSpace(string(output))
+
+	var repository string
+	if u, err := url.Parse(remoteURL); err == nil && u.Host != "" {
+		repository = u.Path
+	} else if _, path, found := strings.Cut(remoteURL, ":"); found {
+		repository = path
+	}
+
+	repository = strings.TrimSuffix(strings.Trim(repository, "/"), ".git")
+	if !strings.Contains(repository, "/") {
+		return "", fmt.Errorf("cannot determine repository from URL %s of git remote %s", remoteURL, remote)
+	}
+
+	return repository, nil
+}
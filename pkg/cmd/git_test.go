@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,3 +55,262 @@ func TestGetVersionWithCommitHashForBranch(t *testing.T) {
 		})
 	}
 }
+
+// mockGitCommandFunc records the git commands run and returns the given status output for 'git status'
+func mockGitCommandFunc(commands *[]string, status string) func(string, ...string) commandExecutor {
+	return func(_ string, arg ...string) commandExecutor {
+		*commands = append(*commands, strings.Join(arg, " "))
+		if arg[0] == "status" {
+			return &MockCommandExecutor{Outcome: status}
+		}
+		return &MockCommandExecutor{}
+	}
+}
+
+func TestEnsureCleanWorkingTree(t *testing.T) {
+	origGitCommandFunc := gitCommandFunc
+	defer func() { gitCommandFunc = origGitCommandFunc }()
+
+	t.Run("clean working tree", func(t *testing.T) {
+		var commands []string
+		gitCommandFunc = mockGitCommandFunc(&commands, "")
+
+		require.NoError(t, ensureCleanWorkingTree("/path/to/project"))
+		assert.Equal(t, []string{"status --porcelain"}, commands)
+	})
+
+	t.Run("dirty working tree", func(t *testing.T) {
+		var commands []string
+		gitCommandFunc = mockGitCommandFunc(&commands, " M go.mod\n")
+
+		err := ensureCleanWorkingTree("/path/to/project")
+		require.EqualError(t, err, "working tree of /path/to/project is not clean: commit or stash your changes first")
+	})
+}
+
+func TestCreateBranch(t *testing.T) {
+	origGitCommandFunc := gitCommandFunc
+	defer func() { gitCommandFunc = origGitCommandFunc }()
+
+	tests := []struct {
+		name             string
+		remote           string
+		upstream         string
+		expectedCommands []string
+	}{
+		{
+			name:             "new branch",
+			expectedCommands: []string{"checkout -b upgrade-deps"},
+		},
+		{
+			name:     "branch of a previous run is reset",
+			remote:   "origin",
+			upstream: "origin/upgrade-deps\n",
+			expectedCommands: []string{
+				"for-each-ref --format=%(upstream:short) refs/heads/upgrade-deps",
+				"checkout -B upgrade-deps",
+			},
+		},
+		{
+			name:     "branch tracking another remote is not reset",
+			remote:   "origin",
+			upstream: "fork/upgrade-deps\n",
+			expectedCommands: []string{
+				"for-each-ref --format=%(upstream:short) refs/heads/upgrade-deps",
+				"checkout -b upgrade-deps",
+			},
+		},
+		{
+			name:   "local branch without upstream is not reset",
+			remote: "origin",
+			expectedCommands: []string{
+				"for-each-ref --format=%(upstream:short) refs/heads/upgrade-deps",
+				"checkout -b upgrade-deps",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []string
+			gitCommandFunc = func(_ string, arg ...string) commandExecutor {
+				commands = append(commands, strings.Join(arg, " "))
+				if arg[0] == "for-each-ref" {
+					return &MockCommandExecutor{Outcome: tt.upstream}
+				}
+				return &MockCommandExecutor{}
+			}
+
+			require.NoError(t, createBranch("/path/to/project", "upgrade-deps", tt.remote))
+			assert.Equal(t, tt.expectedCommands, commands)
+		})
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	t.Run("single dependency", func(t *testing.T) {
+		message := commitMessage([]UpgradeResult{
+			{Package: "sigs.k8s.io/controller-runtime", From: "v0.18.4", To: "v0.19.3", Status: UpgradeStatusUpgraded},
+			{Package: "github.com/openshift/api", Status: UpgradeStatusNotFound},
+		})
+
+		assert.Equal(t, `Upgrade sigs.k8s.io/controller-runtime to v0.19.3
+
+- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3
+`, message)
+	})
+
+	t.Run("multiple dependencies", func(t *testing.T) {
+		message := commitMessage([]UpgradeResult{
+			{Package: "sigs.k8s.io/controller-runtime", From: "v0.18.4", To: "v0.19.3", Status: UpgradeStatusUpgraded},
+			{Package: "sigs.k8s.io/controller-tools", From: "v0.16.5", To: "v0.16.5", Status: UpgradeStatusUpToDate},
+			{Package: "github.com/operator-framework/api", From: "v0.26.0", To: "v0.27.0", Status: UpgradeStatusUpgraded},
+		})
+
+		assert.Equal(t, `Upgrade 2 Go dependencies
+
+- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3
+- github.com/operator-framework/api: v0.26.0 → v0.27.0
+`, message)
+	})
+
+	t.Run("added dependency", func(t *testing.T) {
+		message := commitMessage([]UpgradeResult{
+			{Package: "sigs.k8s.io/controller-tools", To: "v0.16.5", Status: UpgradeStatusAdded},
+		})
+
+		assert.Equal(t, `Add sigs.k8s.io/controller-tools v0.16.5
+
+- sigs.k8s.io/controller-tools: added at v0.16.5
+`, message)
+	})
+}
+
+func TestUpgradeWithGit(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	origGitCommandFunc := gitCommandFunc
+	defer func() {
+		goCommandFunc = origGoCommandFunc
+		gitCommandFunc = origGitCommandFunc
+	}()
+
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"},{"Path":"github.com/operator-framework/api","Version":"v0.26.0"}]}`}
+	}
+
+	config := `dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "github.com/operator-framework/api"
+    version: "v0.27.0"`
+
+	tests := []struct {
+		name             string
+		options          UpgradeOptions
+		status           string
+		expectedCommands []string
+		expectedError    string
+	}{
+		{
+			name:    "create branch and commit once",
+			options: UpgradeOptions{GitBranch: "upgrade-deps", GitCommit: true},
+			expectedCommands: []string{
+				"status --porcelain",
+				"checkout -b upgrade-deps",
+				"add --all .",
+				"commit --quiet --message Upgrade 2 Go dependencies\n\n" +
+					"- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3\n" +
+					"- github.com/operator-framework/api: v0.26.0 → v0.27.0\n",
+			},
+		},
+		{
+			name:    "commit per dependency",
+			options: UpgradeOptions{GitCommitPerDependency: true},
+			expectedCommands: []string{
+				"status --porcelain",
+				"add --all .",
+				"commit --quiet --message Upgrade sigs.k8s.io/controller-runtime to v0.19.3\n\n" +
+					"- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3\n",
+				"add --all .",
+				"commit --quiet --message Upgrade github.com/operator-framework/api to v0.27.0\n\n" +
+					"- github.com/operator-framework/api: v0.26.0 → v0.27.0\n",
+			},
+		},
+		{
+			name:          "refuse to start on a dirty working tree",
+			options:       UpgradeOptions{GitBranch: "upgrade-deps"},
+			status:        "?? notes.txt\n",
+			expectedError: "working tree of /path/to/project is not clean: commit or stash your changes first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+
+			var commands []string
+			gitCommandFunc = mockGitCommandFunc(&commands, tt.status)
+
+			_, err := Upgrade(configPath, "/path/to/project", tt.options)
+
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				assert.Equal(t, []string{"status --porcelain"}, commands)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommands, commands)
+		})
+	}
+}
+
+func TestRepositoryFromRemote(t *testing.T) {
+	origGitCommandFunc := gitCommandFunc
+	defer func() { gitCommandFunc = origGitCommandFunc }()
+
+	tests := []struct {
+		name               string
+		remoteURL          string
+		expectedRepository string
+		expectedError      string
+	}{
+		{
+			name:               "HTTPS remote",
+			remoteURL:          "https://github.com/codeready-toolchain/member-operator.git",
+			expectedRepository: "codeready-toolchain/member-operator",
+		},
+		{
+			name:               "SSH remote",
+			remoteURL:          "git@gitlab.com:platform/operators/member-operator.git",
+			expectedRepository: "platform/operators/member-operator",
+		},
+		{
+			name:               "SSH URL remote",
+			remoteURL:          "ssh://git@github.com/codeready-toolchain/member-operator",
+			expectedRepository: "codeready-toolchain/member-operator",
+		},
+		{
+			name:          "local remote",
+			remoteURL:     "../member-operator",
+			expectedError: "cannot determine repository from URL ../member-operator of git remote origin",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitCommandFunc = func(_ string, _ ...string) commandExecutor {
+				return &MockCommandExecutor{Outcome: test.remoteURL + "\n"}
+			}
+
+			repository, err := repositoryFromRemote("/path/to/project", "origin")
+
+			if test.expectedError == "" {
+				require.NoError(t, err)
+				assert.Equal(t, test.expectedRepository, repository)
+			} else {
+				require.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
@@ -2,11 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -25,26 +26,37 @@ var goCommandFunc = func(isStandard bool, projectPath string, arg ...string) com
 	cmd := exec.Command("go", arg...)
 	cmd.Dir = projectPath
 
+	// the output of go commands is a log of the run, kept out of stdout like the other logs
 	if isStandard {
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = os.Stderr
 		cmd.Stderr = os.Stderr
 	}
 
 	return cmd
 }
 
-func getPackageVersion(targetDir, packageName string) (string, error) {
-	log.Info().Msgf("checking current version for package %s...", packageName)
+// readModule returns the JSON representation of the go.mod file of the project in targetDir.
+func readModule(targetDir string) (*Module, error) {
 	cmd := goCommandFunc(false, targetDir, "mod", "edit", "-json")
 
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("failed to run 'go mod edit': %w", err)
+		return nil, fmt.Errorf("failed to run 'go mod edit': %w", err)
 	}
 
 	var module Module
 	if err := json.Unmarshal(output, &module); err != nil {
-		return "", fmt.Errorf("failed to parse go.mod JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse go.mod JSON: %w", err)
+	}
+
+	return &module, nil
+}
+
+func getPackageVersion(targetDir, packageName string) (string, error) {
+	log.Info().Msgf("checking current version for package %s...", packageName)
+	module, err := readModule(targetDir)
+	if err != nil {
+		return "", err
 	}
 
 	for _, pkg := range module.Require {
@@ -56,26 +68,50 @@ func getPackageVersion(targetDir, packageName string) (string, error) {
 	return "", fmt.Errorf("%w: %s", ErrPackageNotFound, packageName)
 }
 
-// GetKubernetesVersion fetches the go.mod file from the given GitHub raw URL
-// and returns the version of pkg used in that file.
-func GetKubernetesVersion(repo, branch, pkg string) (string, error) {
-	u := &url.URL{
-		Scheme: "https",
-		Host:   "raw.githubusercontent.com",
-		Path:   fmt.Sprintf("%s/%s/go.mod", repo, branch),
+// getGoDirective returns the go and toolchain directives of the project in targetDir.
+func getGoDirective(targetDir string) (string, string, error) {
+	module, err := readModule(targetDir)
+	if err != nil {
+		return "", "", err
 	}
-	resp, err := http.Get(u.String())
+
+	return module.Go, module.Toolchain, nil
+}
+
+// githubRawContentURL serves the raw content of files hosted on GitHub
+var githubRawContentURL = "https://raw.githubusercontent.com"
+
+// fetchGoMod fetches the go.mod file at the root of the given GitHub repository (e.g. openshift/api)
+// for the given branch, tag or commit.
+func fetchGoMod(repo, branch string) ([]byte, error) {
+	return fetchGitHubFile(repo, branch, "go.mod")
+}
+
+// fetchGitHubFile fetches the file at the given path of a GitHub repository for the given branch, tag or commit.
+func fetchGitHubFile(repo, ref, path string) ([]byte, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/%s/%s/%s", githubRawContentURL, repo, ref, path))
 
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch go.mod: %w", err)
+		return nil, fmt.Errorf("failed to fetch %s: %w", path, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("non 200 response: %d", resp.StatusCode)
+		return nil, fmt.Errorf("non 200 response: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// GetKubernetesVersion fetches the go.mod file from the given GitHub raw URL
+// and returns the version of pkg used in that file.
+func GetKubernetesVersion(repo, branch, pkg string) (string, error) {
+	goMod, err := fetchGoMod(repo, branch)
+	if err != nil {
+		return "", err
 	}
 
-	scanner := bufio.NewScanner(resp.Body)
+	scanner := bufio.NewScanner(bytes.NewReader(goMod))
 	inRequireBlock := false
 
 	for scanner.Scan() {
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/rsoaresd/goupgrader/pkg/cmd/flags"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// lockfileName is the name of the lockfile written next to the config
+const lockfileName = "goupgrader.lock"
+
+const (
+	lockSourceVersion = "version"
+	lockSourceBranch  = "branch"
+)
+
+func NewLock() *cobra.Command {
+	var config, lockfile string
+	var update bool
+	var options ConfigOptions
+
+	command := &cobra.Command{
+		Use:   "lock --config=<config-path> [--lockfile=<lockfile-path>] [--update]",
+		Short: "Check or update the lockfile of a config",
+		Long: `The lockfile (goupgrader.lock, next to the config) records the exact version each dependency of the
+config was resolved to, e.g. the pseudo-version of the latest commit of a branch, so that
+'upgrade --locked' upgrades to the same versions later on. A config read from stdin or fetched
+from a URL has no lockfile, unless its path is given with --lockfile.
+
+Without --update, checks that the lockfile exists and was written for the current config.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if update {
+				_, err := UpdateLockfile(config, lockfile, options)
+				return err
+			}
+			if _, err := readLockfile(config, lockfile, options); err != nil {
+				return err
+			}
+			log.Info().Msgf("%s is up to date", lockfilePath(config, lockfile))
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&config, "config", "c", "", "path or URL of the config, or - to read it from stdin")
+	flags.MustMarkRequired(command, "config")
+	command.Flags().StringVar(&lockfile, "lockfile", "", "path of the lockfile (defaults to goupgrader.lock next to the config)")
+	command.Flags().BoolVar(&update, "update", false, "resolve the config again and rewrite the lockfile")
+	addConfigFlags(command, &options)
+
+	return command
+}
+
+// UpdateLockfile resolves the dependencies of the config and writes the lockfile next to it, or to the given lockfile.
+func UpdateLockfile(configPath, lockfile string, options ConfigOptions) (*Lockfile, error) {
+	path := lockfilePath(configPath, lockfile)
+	if path == "" {
+		return nil, noLockfileError(configPath)
+	}
+
+	config, err := parseConfig(configPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, _ := liveDependencies(config)
+	lock, err := resolveDependencies(config, dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, writeLockfile(path, lock)
+}
+
+// lockfilePath returns the path of the lockfile of the given config: the given lockfile if any, else next to the
+// config. A config read from stdin or fetched from a URL has no location to put it next to, so it has no lockfile
+// and an empty path is returned.
+func lockfilePath(configPath, lockfile string) string {
+	switch {
+	case lockfile != "":
+		return lockfile
+	case configPath == stdioPath || isURL(configPath):
+		return ""
+	default:
+		return filepath.Join(filepath.Dir(configPath), lockfileName)
+	}
+}
+
+func noLockfileError(configPath string) error {
+	return fmt.Errorf("config %s has no lockfile next to it, use --lockfile to give its path", configPath)
+}
+
+// lockUpdateCommand returns the command updating the lockfile of the config, for error messages.
+func lockUpdateCommand(configPath, lockfile string) string {
+	if lockfile != "" {
+		return fmt.Sprintf("goupgrader lock --update --config=%s --lockfile=%s", configPath, lockfile)
+	}
+	return fmt.Sprintf("goupgrader lock --update --config=%s", configPath)
+}
+
+// configHash returns the SHA-256 hash of the parsed config, merged with the configs it includes and expanded, so that
+// a change to an included config or to the value of a variable is noticed too.
+func configHash(config *Config) (string, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// resolveDependencies resolves the given dependencies of the config to an exact version: versions are used as is and
+// branches are resolved to the pseudo-version of their latest commit.
+func resolveDependencies(config *Config, dependencies []Dependency) (*Lockfile, error) {
+	hash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lockfile{
+		ConfigHash: hash,
+		Resolved:   time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, dependency := range dependencies {
+		locked := LockedDependency{
+			Package:      dependency.Package,
+			Version:      dependency.Version,
+			Source:       lockSourceVersion,
+			Group:        dependency.Group,
+			AddIfMissing: dependency.AddIfMissing,
+		}
+		if dependency.Branch != "" {
+			locked.Version, err = branchVersionFunc(dependency.Package, dependency.Branch)
+			if err != nil {
+				return nil, err
+			}
+			locked.Source = fmt.Sprintf("%s %s", lockSourceBranch, dependency.Branch)
+		}
+		lock.Dependencies = append(lock.Dependencies, locked)
+	}
+
+	return lock, nil
+}
+
+// writeLockfile writes the lockfile to the given path. A lockfile resolved the same way is left untouched, keeping
+// the time it was first resolved, so that a run upgrading nothing leaves no change behind.
+func writeLockfile(path string, lock *Lockfile) error {
+	if data, err := os.ReadFile(path); err == nil {
+		var existing Lockfile
+		if yaml.Unmarshal(data, &existing) == nil && existing.ConfigHash == lock.ConfigHash &&
+			slices.Equal(existing.Dependencies, lock.Dependencies) {
+			lock.Resolved = existing.Resolved
+			log.Info().Msgf("lockfile %s is up to date", path)
+			return nil
+		}
+	}
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	log.Info().Msgf("lockfile saved to %s", path)
+
+	return nil
+}
+
+// readLockfile parses the lockfile of the config, next to it or the given lockfile, and checks that it was written for
+// the current contents of the config.
+func readLockfile(configPath, lockfile string, options ConfigOptions) (*Lockfile, error) {
+	path := lockfilePath(configPath, lockfile)
+	if path == "" {
+		return nil, noLockfileError(configPath)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("lockfile %s not found, run '%s' to create it", path, lockUpdateCommand(configPath, lockfile))
+	} else if err != nil {
+		return nil, err
+	}
+
+	var lock Lockfile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+
+	config, err := parseConfig(configPath, options)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+	if hash != lock.ConfigHash {
+		return nil, fmt.Errorf("config %s changed since %s was written, run '%s' to update it",
+			configPath, path, lockUpdateCommand(configPath, lockfile))
+	}
+
+	return &lock, nil
+}
+
+// lockedDependencies returns the dependencies to upgrade to the exact versions of the lockfile.
+func lockedDependencies(lock *Lockfile) []Dependency {
+	var dependencies []Dependency
+	for _, locked := range lock.Dependencies {
+		dependencies = append(dependencies, Dependency{
+			Package:      locked.Package,
+			Version:      locked.Version,
+			Group:        locked.Group,
+			AddIfMissing: locked.AddIfMissing,
+		})
+	}
+	return dependencies
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lockTestConfig = `dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "github.com/openshift/api"
+    branch: "release-4.18"
+  - package: "k8s.io/api"
+    version: "v0.31.1"
+    group: kubernetes
+`
+
+// fakeBranchVersions replaces the resolution of branches by the given pseudo-version and counts the resolutions
+func fakeBranchVersions(t *testing.T, version string) *int {
+	t.Helper()
+	origBranchVersionFunc := branchVersionFunc
+	t.Cleanup(func() { branchVersionFunc = origBranchVersionFunc })
+
+	resolutions := 0
+	branchVersionFunc = func(_, _ string) (string, error) {
+		resolutions++
+		return version, nil
+	}
+	return &resolutions
+}
+
+func TestUpdateLockfile(t *testing.T) {
+	fakeBranchVersions(t, "v0.0.0-20250301100000-0123456789ab")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(lockTestConfig), 0600))
+
+	lock, err := UpdateLockfile(configPath, "", ConfigOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []LockedDependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3", Source: "version"},
+		{Package: "github.com/openshift/api", Version: "v0.0.0-20250301100000-0123456789ab", Source: "branch release-4.18"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Source: "version", Group: "kubernetes"},
+	}, lock.Dependencies)
+	assert.True(t, strings.HasPrefix(lock.ConfigHash, "sha256:"))
+	assert.NotEmpty(t, lock.Resolved)
+
+	written, err := readLockfile(configPath, "", ConfigOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, lock, written)
+	assert.FileExists(t, filepath.Join(filepath.Dir(configPath), "goupgrader.lock"))
+
+	t.Run("config changed", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configPath, []byte(lockTestConfig+"  - package: \"k8s.io/client-go\"\n    version: \"v0.31.1\"\n"), 0600))
+
+		_, err := readLockfile(configPath, "", ConfigOptions{})
+
+		lockPath := filepath.Join(filepath.Dir(configPath), "goupgrader.lock")
+		require.EqualError(t, err, fmt.Sprintf("config %s changed since %s was written, run 'goupgrader lock --update --config=%s' to update it",
+			configPath, lockPath, configPath))
+	})
+
+	t.Run("missing lockfile", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(lockTestConfig), 0600))
+
+		cmd := NewLock()
+		cmd.SetArgs([]string{"--config=" + configPath})
+		err := cmd.Execute()
+
+		require.EqualError(t, err, fmt.Sprintf("lockfile %s not found, run 'goupgrader lock --update --config=%s' to create it",
+			filepath.Join(filepath.Dir(configPath), "goupgrader.lock"), configPath))
+	})
+}
+
+func TestLockfilePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("configs", "goupgrader.lock"), lockfilePath(filepath.Join("configs", "config.yaml"), ""))
+	assert.Equal(t, "deps.lock", lockfilePath(filepath.Join("configs", "config.yaml"), "deps.lock"))
+	assert.Empty(t, lockfilePath("-", ""))
+	assert.Empty(t, lockfilePath("https://example.com/config.yaml", ""))
+	assert.Equal(t, "deps.lock", lockfilePath("-", "deps.lock"))
+}
+
+func TestUpgradeLocked(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	t.Cleanup(func() { goCommandFunc = origGoCommandFunc })
+	var commands []string
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		if arg[0] == "get" {
+			commands = append(commands, strings.Join(arg, " "))
+		}
+		return &MockCommandExecutor{
+			Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"},` +
+				`{"Path":"github.com/openshift/api","Version":"v0.0.0-20250101100000-aaaaaaaaaaaa"},{"Path":"k8s.io/api","Version":"v0.30.1"}]}`,
+		}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(lockTestConfig), 0600))
+
+	// a first run resolves the branch and writes the lockfile
+	resolutions := fakeBranchVersions(t, "v0.0.0-20250301100000-0123456789ab")
+	_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *resolutions)
+	assert.FileExists(t, lockfilePath(configPath, ""))
+
+	// the branch moved on since, but a locked run sticks to the version of the lockfile
+	commands = nil
+	resolutions = fakeBranchVersions(t, "v0.0.0-20250315100000-bbbbbbbbbbbb")
+
+	report, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{Locked: true})
+
+	require.NoError(t, err)
+	assert.Zero(t, *resolutions)
+	assert.Equal(t, []string{
+		"get sigs.k8s.io/controller-runtime@v0.19.3",
+		"get github.com/openshift/api@v0.0.0-20250301100000-0123456789ab",
+		"get k8s.io/api@v0.31.1",
+	}, commands)
+	assert.Len(t, report.Results, 3)
+
+	t.Run("config changed", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configPath, []byte(strings.Replace(lockTestConfig, "v0.19.3", "v0.19.4", 1)), 0600))
+
+		_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{Locked: true})
+
+		require.ErrorContains(t, err, fmt.Sprintf("config %s changed since", configPath))
+	})
+}
+
+func TestUpgradeNothingUpgraded(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	origGitCommandFunc := gitCommandFunc
+	t.Cleanup(func() {
+		goCommandFunc = origGoCommandFunc
+		gitCommandFunc = origGitCommandFunc
+	})
+	// the project is already at the versions of the config
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{
+			Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.19.3"},` +
+				`{"Path":"github.com/openshift/api","Version":"v0.0.0-20250301100000-0123456789ab"},{"Path":"k8s.io/api","Version":"v0.31.1"}]}`,
+		}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(lockTestConfig), 0600))
+	fakeBranchVersions(t, "v0.0.0-20250301100000-0123456789ab")
+	lock, err := UpdateLockfile(configPath, "", ConfigOptions{})
+	require.NoError(t, err)
+	lock.Resolved = "2025-03-01T10:00:00Z"
+	require.NoError(t, os.Remove(lockfilePath(configPath, "")))
+	require.NoError(t, writeLockfile(lockfilePath(configPath, ""), lock))
+	before, err := os.ReadFile(lockfilePath(configPath, ""))
+	require.NoError(t, err)
+
+	var commands []string
+	gitCommandFunc = mockGitCommandFunc(&commands, "")
+
+	_, err = Upgrade(configPath, "/path/to/project", UpgradeOptions{GitCommit: true})
+
+	// nothing is committed and the lockfile is left as it was, so that the next run starts from a clean working tree
+	require.NoError(t, err)
+	assert.Equal(t, []string{"status --porcelain"}, commands)
+	after, err := os.ReadFile(lockfilePath(configPath, ""))
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+
+	t.Run("branch moved on", func(t *testing.T) {
+		fakeBranchVersions(t, "v0.0.0-20250315100000-bbbbbbbbbbbb")
+
+		lock, err := UpdateLockfile(configPath, "", ConfigOptions{})
+
+		require.NoError(t, err)
+		assert.NotEqual(t, "2025-03-01T10:00:00Z", lock.Resolved)
+		written, err := readLockfile(configPath, "", ConfigOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, lock, written)
+	})
+}
+
+func TestUpgradeLockedStdin(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	origReadStdin := readStdin
+	t.Cleanup(func() {
+		goCommandFunc = origGoCommandFunc
+		readStdin = origReadStdin
+	})
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+	readStdin = func() ([]byte, error) { return []byte(lockTestConfig), nil }
+	fakeBranchVersions(t, "v0.0.0-20250301100000-0123456789ab")
+
+	// the current directory is the project in a pipeline
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+
+	_, err = Upgrade("-", dir, UpgradeOptions{})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "goupgrader.lock"))
+
+	_, err = Upgrade("-", dir, UpgradeOptions{Locked: true})
+	require.EqualError(t, err, "config - has no lockfile next to it, use --lockfile to give its path")
+
+	t.Run("explicit lockfile", func(t *testing.T) {
+		lockfile := filepath.Join(t.TempDir(), "deps.lock")
+
+		_, err := Upgrade("-", dir, UpgradeOptions{Lockfile: lockfile})
+		require.NoError(t, err)
+		assert.FileExists(t, lockfile)
+
+		_, err = Upgrade("-", dir, UpgradeOptions{Locked: true, Lockfile: lockfile})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/rsoaresd/goupgrader/pkg/cmd/flags"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v2"
+)
+
+// matrixFormatVersion is the version of the compatibility matrix file format
+const matrixFormatVersion = 1
+
+// seedMatrix is the compatibility matrix built into goupgrader, used until the user refreshes their own.
+// It is updated with 'make seed-matrix'.
+//
+//go:embed seed_matrix.yaml
+var seedMatrix []byte
+
+func NewMatrix() *cobra.Command {
+	var from, to, format string
+	var options GenerateOptions
+
+	command := &cobra.Command{
+		Use:   "matrix --from=<openshift-version> [--to=<openshift-version>] [--format=table|csv|json]",
+		Short: "Print the Kubernetes, operator-sdk and dependency versions matching a range of OpenShift releases",
+		Long: `Prints, for each OpenShift release of the range, the Kubernetes version it uses, the highest matching
+operator-sdk release and the versions of the dependencies derived from it, as a table, CSV or JSON.
+
+The compatibility matrix records these versions, so that they can be printed with --offline and used by
+'generate --offline' instead of looking them up upstream. Use 'matrix refresh' to update it.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return PrintMatrix(cmd.OutOrStdout(), from, to, MatrixFormat(format), options)
+		},
+	}
+	command.Flags().StringVar(&from, "from", "", "first OpenShift version of the range, e.g. 4.14")
+	flags.MustMarkRequired(command, "from")
+	command.Flags().StringVar(&to, "to", "", "last OpenShift version of the range (defaults to --from)")
+	command.Flags().StringVar(&format, "format", string(MatrixFormatTable), "output format: table, csv or json")
+	command.Flags().BoolVar(&options.IncludePrereleases, "include-prereleases", false, "also consider operator-sdk prereleases (e.g. release candidates)")
+	command.Flags().BoolVar(&options.Offline, "offline", false, "read the versions from the compatibility matrix instead of upstream")
+	command.Flags().StringVar(&options.MatrixPath, "matrix", defaultMatrixPath(), "compatibility matrix file used with --offline")
+
+	command.AddCommand(NewMatrixRefresh())
+
+	return command
+}
+
+func NewMatrixRefresh() *cobra.Command {
+	var from, to, matrixPath string
+	var includePrereleases bool
+
+	command := &cobra.Command{
+		Use:   "refresh --from=<openshift-version> [--to=<openshift-version>]",
+		Short: "Rebuild the compatibility matrix entries of a range of OpenShift releases from upstream",
+		Long: `Looks up the Kubernetes version used by each OpenShift release of the range, the matching operator-sdk
+release and the dependency versions required by its go.mod, and saves them in the compatibility matrix.
+Entries of other OpenShift releases already in the matrix are kept.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return RefreshMatrix(from, to, matrixPath, includePrereleases)
+		},
+	}
+	command.Flags().StringVar(&from, "from", "", "first OpenShift version of the range, e.g. 4.14")
+	flags.MustMarkRequired(command, "from")
+	command.Flags().StringVar(&to, "to", "", "last OpenShift version of the range (defaults to --from)")
+	command.Flags().StringVar(&matrixPath, "matrix", defaultMatrixPath(), "compatibility matrix file to update")
+	command.Flags().BoolVar(&includePrereleases, "include-prereleases", false, "also consider operator-sdk prereleases (e.g. release candidates)")
+
+	return command
+}
+
+// RefreshMatrix rebuilds the compatibility matrix entries of the OpenShift versions from..to and saves the matrix.
+func RefreshMatrix(from, to, matrixPath string, includePrereleases bool) error {
+	openshiftVersions, err := openshiftVersionRange(from, to)
+	if err != nil {
+		return err
+	}
+
+	// a new matrix starts from the built-in one
+	matrix, err := loadMatrix(matrixPath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := buildMatrixEntries(openshiftVersions, includePrereleases)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		matrix.setEntry(entry)
+	}
+	matrix.Version = matrixFormatVersion
+	matrix.Refreshed = time.Now().UTC().Format(time.RFC3339)
+
+	return saveMatrix(matrix, matrixPath)
+}
+
+// PrintMatrix prints the versions matching the OpenShift versions from..to in the given format, looking them up
+// upstream or, in offline mode, in the compatibility matrix.
+func PrintMatrix(out io.Writer, from, to string, format MatrixFormat, options GenerateOptions) error {
+	if err := validateMatrixFormat(format); err != nil {
+		return err
+	}
+
+	openshiftVersions, err := openshiftVersionRange(from, to)
+	if err != nil {
+		return err
+	}
+
+	var entries []MatrixEntry
+	if options.Offline {
+		matrix, err := loadMatrix(options.MatrixPath)
+		if err != nil {
+			return err
+		}
+		for _, openshiftVersion := range openshiftVersions {
+			entry, found := matrix.entry(openshiftVersion)
+			if !found {
+				return missingMatrixEntryError(options.MatrixPath, openshiftVersion)
+			}
+			entries = append(entries, *entry)
+		}
+	} else {
+		entries, err = buildMatrixEntries(openshiftVersions, options.IncludePrereleases)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeMatrix(out, entries, format)
+}
+
+// matrixColumns are the headers of the table and CSV formats
+var matrixColumns = []string{"OpenShift", "Kubernetes", "operator-sdk", "controller-runtime", "controller-tools", "operator-framework/api", "operator-registry"}
+
+// writeMatrix writes the entries in the given format. The Kubernetes modules are only included in JSON.
+func writeMatrix(out io.Writer, entries []MatrixEntry, format MatrixFormat) error {
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, []string{entry.Openshift, entry.Kubernetes, entry.OperatorSdk, entry.ControllerRuntime,
+			entry.ControllerTools, entry.OperatorFrameworkAPI, entry.OperatorRegistry})
+	}
+
+	switch format {
+	case MatrixFormatCSV:
+		writer := csv.NewWriter(out)
+		if err := writer.Write(matrixColumns); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+		return nil
+	case MatrixFormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []MatrixEntry{}
+		}
+		return encoder.Encode(entries)
+	default:
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(matrixColumns, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+// validateMatrixFormat checks if the output format of the matrix command is known.
+func validateMatrixFormat(format MatrixFormat) error {
+	switch format {
+	case MatrixFormatTable, MatrixFormatCSV, MatrixFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q: must be one of %s, %s, %s", format, MatrixFormatTable, MatrixFormatCSV, MatrixFormatJSON)
+	}
+}
+
+// defaultMatrixPath returns the path of the compatibility matrix in the user configuration directory,
+// or in the current directory if there is none.
+func defaultMatrixPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "goupgrader-matrix.yaml"
+	}
+	return filepath.Join(dir, "goupgrader", "matrix.yaml")
+}
+
+// loadMatrix parses the compatibility matrix file at the given path, or the built-in matrix if there is none.
+func loadMatrix(matrixPath string) (*CompatibilityMatrix, error) {
+	data, err := os.ReadFile(matrixPath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info().Msgf("compatibility matrix %s not found: using the built-in one, run 'goupgrader matrix refresh' to update it", matrixPath)
+		return parseMatrix(seedMatrix, "built-in compatibility matrix")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return parseMatrix(data, fmt.Sprintf("compatibility matrix %s", matrixPath))
+}
+
+// parseMatrix parses a compatibility matrix, named in errors as given.
+func parseMatrix(data []byte, name string) (*CompatibilityMatrix, error) {
+	var matrix CompatibilityMatrix
+	if err := yaml.Unmarshal(data, &matrix); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	if matrix.Version != matrixFormatVersion {
+		return nil, fmt.Errorf("%s: unsupported version %d, run 'goupgrader matrix refresh' to rebuild it", name, matrix.Version)
+	}
+
+	return &matrix, nil
+}
+
+// saveMatrix writes the compatibility matrix to the given path, creating its directory if needed.
+func saveMatrix(matrix *CompatibilityMatrix, matrixPath string) error {
+	data, err := yaml.Marshal(matrix)
+	if err != nil {
+		return fmt.Errorf("failed to marshal compatibility matrix: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(matrixPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory of %s: %w", matrixPath, err)
+	}
+
+	if err := os.WriteFile(matrixPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	log.Info().Msgf("compatibility matrix saved to %s", matrixPath)
+
+	return nil
+}
+
+// entry returns the entry of the given OpenShift version, if any.
+func (m *CompatibilityMatrix) entry(openshiftVersion string) (*MatrixEntry, bool) {
+	for i := range m.Entries {
+		if m.Entries[i].Openshift == openshiftVersion {
+			return &m.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// setEntry adds the entry to the matrix, or replaces the one of the same OpenShift version,
+// keeping the entries sorted by OpenShift version.
+func (m *CompatibilityMatrix) setEntry(entry MatrixEntry) {
+	if existing, found := m.entry(entry.Openshift); found {
+		*existing = entry
+		return
+	}
+
+	m.Entries = append(m.Entries, entry)
+	sort.Slice(m.Entries, func(i, j int) bool {
+		return semver.Compare("v"+m.Entries[i].Openshift, "v"+m.Entries[j].Openshift) < 0
+	})
+}
+
+func missingMatrixEntryError(matrixPath, openshiftVersion string) error {
+	return fmt.Errorf("compatibility matrix %s has no entry for Openshift %s, run 'goupgrader matrix refresh --from=%s' to add it",
+		matrixPath, openshiftVersion, openshiftVersion)
+}
+
+// moduleVersion returns the version of the given module recorded in the entry, or an empty string if there is none.
+func (e *MatrixEntry) moduleVersion(modulePath string) string {
+	switch modulePath {
+	case operatorSdkModule:
+		return e.OperatorSdk
+	case "sigs.k8s.io/controller-runtime":
+		return e.ControllerRuntime
+	case "sigs.k8s.io/controller-tools":
+		return e.ControllerTools
+	case "github.com/operator-framework/api":
+		return e.OperatorFrameworkAPI
+	case "github.com/operator-framework/operator-registry":
+		return e.OperatorRegistry
+	}
+	return ""
+}
+
+// openshiftVersionRange returns the OpenShift versions from..to, e.g. 4.14, 4.15 and 4.16 for 4.14..4.16.
+// If to is empty, only from is returned.
+func openshiftVersionRange(from, to string) ([]string, error) {
+	if to == "" {
+		to = from
+	}
+
+	for _, version := range []string{from, to} {
+		if strings.Count(version, ".") != 1 || semver.Canonical("v"+version) != semver.MajorMinor("v"+version)+".0" {
+			return nil, fmt.Errorf("invalid OpenShift version %q: must be <major>.<minor>", version)
+		}
+	}
+
+	fromMajor, fromMinor := versionNumbers("v" + from)
+	toMajor, toMinor := versionNumbers("v" + to)
+	if fromMajor != toMajor || fromMinor > toMinor {
+		return nil, fmt.Errorf("invalid OpenShift version range %s..%s", from, to)
+	}
+
+	var versions []string
+	for minor := fromMinor; minor <= toMinor; minor++ {
+		versions = append(versions, fmt.Sprintf("%d.%d", fromMajor, minor))
+	}
+
+	return versions, nil
+}
+
+// buildMatrixEntries looks up the Kubernetes version, the highest matching operator-sdk release and the dependency
+// versions it requires for each of the OpenShift versions.
+func buildMatrixEntries(openshiftVersions []string, includePrereleases bool) ([]MatrixEntry, error) {
+	entries := make([]MatrixEntry, len(openshiftVersions))
+	oldestK8sVersion := ""
+	for i, openshiftVersion := range openshiftVersions {
+		k8sVersion, err := getKubernetesVersionUsedByOpenshift(openshiftVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Kubernetes version of Openshift %s: %w", openshiftVersion, err)
+		}
+		entries[i] = MatrixEntry{Openshift: openshiftVersion, Kubernetes: k8sVersion}
+
+		if oldestK8sVersion == "" || compareMinorVersions(k8sVersion, oldestK8sVersion) < 0 {
+			oldestK8sVersion = k8sVersion
+		}
+	}
+
+	// check every operator-sdk release once, down to the oldest Kubernetes version of the range
+	releases, err := listOperatorSdkReleases(includePrereleases)
+	if err != nil {
+		return nil, err
+	}
+	candidates := collectOperatorSdkCandidates(releases, oldestK8sVersion)
+
+	for i := range entries {
+		entry := &entries[i]
+		entry.OperatorSdk, err = selectOperatorSdkVersion(candidates, entry.Kubernetes, "", MatchHighest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the operator-sdk release matching Openshift %s: %w", entry.Openshift, err)
+		}
+
+		for _, dep := range []struct {
+			modulePath string
+			version    *string
+		}{
+			{"sigs.k8s.io/controller-runtime", &entry.ControllerRuntime},
+			{"sigs.k8s.io/controller-tools", &entry.ControllerTools},
+			{"github.com/operator-framework/api", &entry.OperatorFrameworkAPI},
+			{"github.com/operator-framework/operator-registry", &entry.OperatorRegistry},
+		} {
+			*dep.version, err = GetKubernetesVersion("operator-framework/operator-sdk", entry.OperatorSdk, dep.modulePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get version for %s: %w", dep.modulePath, err)
+			}
+		}
+
+		k8sDependencies, err := getKubernetesDependenciesUsedByOpenshift(entry.Openshift)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range k8sDependencies {
+			entry.KubernetesModules = append(entry.KubernetesModules, Package{Path: dep.Package, Version: dep.Version})
+		}
+	}
+
+	return entries, nil
+}
+
+// generateConfigFromMatrix builds the config of the profile dependencies for the given Openshift version from
+// the compatibility matrix, without looking up anything upstream.
+func generateConfigFromMatrix(openshiftVersion string, profile *Profile, matrixPath string) (*Config, error) {
+	matrix, err := loadMatrix(matrixPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, found := matrix.entry(openshiftVersion)
+	if !found {
+		return nil, missingMatrixEntryError(matrixPath, openshiftVersion)
+	}
+	log.Info().Msgf("using the compatibility matrix %s (refreshed %s): Openshift %s uses Kubernetes %s, matching operator-sdk %s",
+		matrixPath, matrix.Refreshed, openshiftVersion, entry.Kubernetes, entry.OperatorSdk)
+
+	config, err := buildProfileConfig(profile, openshiftVersion, func(pkg string) (string, error) {
+		version := entry.moduleVersion(pkg)
+		if version == "" {
+			return "", fmt.Errorf("not recorded in the compatibility matrix")
+		}
+		return version, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error building config: %w", err)
+	}
+
+	var k8sDependencies []Dependency
+	for _, module := range entry.KubernetesModules {
+		k8sDependencies = append(k8sDependencies, Dependency{Package: module.Path, Version: module.Version, Group: kubernetesGroup})
+	}
+	config.Dependencies = appendMissingDependencies(config.Dependencies, k8sDependencies)
+
+	return &config, nil
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openshiftAPIGoMod returns the go.mod of an openshift/api release branch using the given Kubernetes version
+func openshiftAPIGoMod(k8sVersion string) string {
+	return fmt.Sprintf(`module github.com/openshift/api
+
+require (
+	k8s.io/api %[1]s
+	k8s.io/apimachinery %[1]s
+)
+`, k8sVersion)
+}
+
+// fullOperatorSdkGoMod returns the go.mod of an operator-sdk release requiring all the modules of the matrix
+func fullOperatorSdkGoMod(k8sVersion, controllerRuntimeVersion string) string {
+	return fmt.Sprintf(`module github.com/operator-framework/operator-sdk
+
+require (
+	github.com/operator-framework/api v0.27.0
+	github.com/operator-framework/operator-registry v1.49.0
+	k8s.io/api %s
+	sigs.k8s.io/controller-runtime %s
+	sigs.k8s.io/controller-tools v0.16.5
+)
+`, k8sVersion, controllerRuntimeVersion)
+}
+
+const testMatrix = `version: 1
+refreshed: "2025-03-01T10:00:00Z"
+entries:
+- openshift: "4.18"
+  kubernetes: v0.31.1
+  operatorSdk: v1.39.1
+  controllerRuntime: v0.19.4
+  controllerTools: v0.16.5
+  operatorFrameworkApi: v0.27.0
+  operatorRegistry: v1.49.0
+  kubernetesModules:
+  - path: k8s.io/api
+    version: v0.31.1
+  - path: k8s.io/apimachinery
+    version: v0.31.1
+`
+
+// useSeedMatrix replaces the built-in compatibility matrix for the duration of the test
+func useSeedMatrix(t *testing.T, matrix string) {
+	t.Helper()
+	origSeedMatrix := seedMatrix
+	seedMatrix = []byte(matrix)
+	t.Cleanup(func() { seedMatrix = origSeedMatrix })
+}
+
+func TestSeedMatrix(t *testing.T) {
+	matrix, err := parseMatrix(seedMatrix, "built-in compatibility matrix")
+
+	require.NoError(t, err)
+	// every entry comes from 'make seed-matrix', which records all the versions it looks up
+	if len(matrix.Entries) > 0 {
+		assert.NotEmpty(t, matrix.Refreshed)
+	}
+	for _, entry := range matrix.Entries {
+		_, err := openshiftVersionRange(entry.Openshift, "")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, entry.Kubernetes, entry.Openshift)
+		assert.NotEmpty(t, entry.OperatorSdk, entry.Openshift)
+		assert.NotEmpty(t, entry.ControllerRuntime, entry.Openshift)
+		assert.NotEmpty(t, entry.ControllerTools, entry.Openshift)
+		assert.NotEmpty(t, entry.OperatorFrameworkAPI, entry.Openshift)
+		assert.NotEmpty(t, entry.OperatorRegistry, entry.Openshift)
+		assert.NotEmpty(t, entry.KubernetesModules, entry.Openshift)
+	}
+}
+
+func TestOpenshiftVersionRange(t *testing.T) {
+	versions, err := openshiftVersionRange("4.14", "4.17")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4.14", "4.15", "4.16", "4.17"}, versions)
+
+	versions, err = openshiftVersionRange("4.18", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4.18"}, versions)
+
+	_, err = openshiftVersionRange("4.18.2", "")
+	require.EqualError(t, err, `invalid OpenShift version "4.18.2": must be <major>.<minor>`)
+
+	_, err = openshiftVersionRange("4.18", "4.16")
+	require.EqualError(t, err, "invalid OpenShift version range 4.18..4.16")
+}
+
+func TestRefreshMatrix(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"github.com/operator-framework/operator-sdk/@v/list": "v1.38.0\nv1.39.0\nv1.39.1\nv1.40.0\n",
+	})
+	newFakeGitHubRawContent(t, map[string]string{
+		"openshift/api/release-4.17/go.mod":              openshiftAPIGoMod("v0.30.2"),
+		"openshift/api/release-4.18/go.mod":              openshiftAPIGoMod("v0.31.1"),
+		"operator-framework/operator-sdk/v1.38.0/go.mod": fullOperatorSdkGoMod("v0.30.3", "v0.18.5"),
+		"operator-framework/operator-sdk/v1.39.0/go.mod": fullOperatorSdkGoMod("v0.31.0", "v0.19.0"),
+		"operator-framework/operator-sdk/v1.39.1/go.mod": fullOperatorSdkGoMod("v0.31.2", "v0.19.4"),
+		"operator-framework/operator-sdk/v1.40.0/go.mod": fullOperatorSdkGoMod("v0.32.1", "v0.20.4"),
+	})
+	useSeedMatrix(t, "version: 1\nentries: []\n")
+	matrixPath := filepath.Join(t.TempDir(), "goupgrader", "matrix.yaml")
+
+	err := RefreshMatrix("4.18", "", matrixPath, false)
+	require.NoError(t, err)
+
+	matrix, err := loadMatrix(matrixPath)
+	require.NoError(t, err)
+	assert.Equal(t, []MatrixEntry{{
+		Openshift:            "4.18",
+		Kubernetes:           "v0.31.1",
+		OperatorSdk:          "v1.39.1",
+		ControllerRuntime:    "v0.19.4",
+		ControllerTools:      "v0.16.5",
+		OperatorFrameworkAPI: "v0.27.0",
+		OperatorRegistry:     "v1.49.0",
+		KubernetesModules:    []Package{{Path: "k8s.io/api", Version: "v0.31.1"}, {Path: "k8s.io/apimachinery", Version: "v0.31.1"}},
+	}}, matrix.Entries)
+	assert.NotEmpty(t, matrix.Refreshed)
+
+	t.Run("keeps the other entries sorted", func(t *testing.T) {
+		err := RefreshMatrix("4.17", "4.18", matrixPath, false)
+		require.NoError(t, err)
+
+		matrix, err := loadMatrix(matrixPath)
+		require.NoError(t, err)
+		require.Len(t, matrix.Entries, 2)
+		assert.Equal(t, "4.17", matrix.Entries[0].Openshift)
+		assert.Equal(t, "v1.38.0", matrix.Entries[0].OperatorSdk)
+		assert.Equal(t, "v0.18.5", matrix.Entries[0].ControllerRuntime)
+		assert.Equal(t, "4.18", matrix.Entries[1].Openshift)
+	})
+
+	t.Run("no matching operator-sdk release", func(t *testing.T) {
+		newFakeGitHubRawContent(t, map[string]string{
+			"openshift/api/release-4.19/go.mod":              openshiftAPIGoMod("v0.33.0"),
+			"operator-framework/operator-sdk/v1.40.0/go.mod": fullOperatorSdkGoMod("v0.32.1", "v0.20.4"),
+		})
+
+		err := RefreshMatrix("4.19", "", matrixPath, false)
+
+		require.EqualError(t, err, "failed to find the operator-sdk release matching Openshift 4.19: "+
+			"no matching operator-sdk version found for Kubernetes v0.33.0: the newest operator-sdk release, v1.40.0, still uses Kubernetes v0.32.1")
+	})
+}
+
+func TestGenerateConfigOffline(t *testing.T) {
+	matrixPath := filepath.Join(t.TempDir(), "matrix.yaml")
+	require.NoError(t, os.WriteFile(matrixPath, []byte(testMatrix), 0600))
+	output := filepath.Join(t.TempDir(), "config.yaml")
+
+	cmd := NewGenerateConfigForOpenshiftDependencies()
+	cmd.SetArgs([]string{"--target-openshift-version=4.18", "--offline", "--matrix=" + matrixPath, "--output=" + output})
+	err := cmd.Execute()
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, `dependencies:
+- package: sigs.k8s.io/controller-runtime
+  version: v0.19.4
+- package: github.com/operator-framework/api
+  version: v0.27.0
+- package: github.com/operator-framework/operator-registry
+  version: v1.49.0
+- package: sigs.k8s.io/controller-tools
+  version: v0.16.5
+- package: github.com/openshift/api
+  branch: release-4.18
+- package: github.com/openshift/library-go
+  branch: release-4.18
+- package: k8s.io/api
+  version: v0.31.1
+  group: kubernetes
+- package: k8s.io/apimachinery
+  version: v0.31.1
+  group: kubernetes
+`, string(content))
+
+	t.Run("Openshift version not in the matrix", func(t *testing.T) {
+		err := GenerateConfigForOpenshiftDependencies("4.17", "", output, GenerateOptions{Offline: true, MatrixPath: matrixPath})
+		require.EqualError(t, err, fmt.Sprintf("compatibility matrix %s has no entry for Openshift 4.17, run 'goupgrader matrix refresh --from=4.17' to add it", matrixPath))
+	})
+
+	t.Run("package not in the matrix", func(t *testing.T) {
+		profilePath := filepath.Join(t.TempDir(), "profile.yaml")
+		require.NoError(t, os.WriteFile(profilePath, []byte("dependencies:\n- package: github.com/onsi/ginkgo/v2\n  track: version\n"), 0600))
+
+		err := GenerateConfigForOpenshiftDependencies("4.18", "", output, GenerateOptions{Offline: true, MatrixPath: matrixPath, ProfilePath: profilePath})
+
+		require.EqualError(t, err, "error building config: failed to get version for github.com/onsi/ginkgo/v2: not recorded in the compatibility matrix")
+	})
+
+	t.Run("built-in matrix", func(t *testing.T) {
+		useSeedMatrix(t, testMatrix)
+		builtIn := filepath.Join(t.TempDir(), "config.yaml")
+
+		err := GenerateConfigForOpenshiftDependencies("4.18", "", builtIn, GenerateOptions{Offline: true, MatrixPath: filepath.Join(t.TempDir(), "matrix.yaml")})
+
+		require.NoError(t, err)
+		generated, err := os.ReadFile(builtIn)
+		require.NoError(t, err)
+		assert.Equal(t, string(content), string(generated))
+	})
+}
+
+func TestPrintMatrix(t *testing.T) {
+	matrixPath := filepath.Join(t.TempDir(), "matrix.yaml")
+	require.NoError(t, os.WriteFile(matrixPath, []byte(testMatrix), 0600))
+	options := GenerateOptions{Offline: true, MatrixPath: matrixPath}
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		cmd := NewMatrix()
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"--from=4.18", "--offline", "--matrix=" + matrixPath})
+
+		err := cmd.Execute()
+
+		require.NoError(t, err)
+		assert.Equal(t, `OpenShift  Kubernetes  operator-sdk  controller-runtime  controller-tools  operator-framework/api  operator-registry
+4.18       v0.31.1     v1.39.1       v0.19.4             v0.16.5           v0.27.0                 v1.49.0
+`, out.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var out bytes.Buffer
+		err := PrintMatrix(&out, "4.18", "", MatrixFormatCSV, options)
+
+		require.NoError(t, err)
+		assert.Equal(t, `OpenShift,Kubernetes,operator-sdk,controller-runtime,controller-tools,operator-framework/api,operator-registry
+4.18,v0.31.1,v1.39.1,v0.19.4,v0.16.5,v0.27.0,v1.49.0
+`, out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		err := PrintMatrix(&out, "4.18", "", MatrixFormatJSON, options)
+
+		require.NoError(t, err)
+		var entries []MatrixEntry
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+		assert.Equal(t, []MatrixEntry{{
+			Openshift:            "4.18",
+			Kubernetes:           "v0.31.1",
+			OperatorSdk:          "v1.39.1",
+			ControllerRuntime:    "v0.19.4",
+			ControllerTools:      "v0.16.5",
+			OperatorFrameworkAPI: "v0.27.0",
+			OperatorRegistry:     "v1.49.0",
+			KubernetesModules:    []Package{{Path: "k8s.io/api", Version: "v0.31.1"}, {Path: "k8s.io/apimachinery", Version: "v0.31.1"}},
+		}}, entries)
+	})
+
+	t.Run("looked up upstream", func(t *testing.T) {
+		newFakeModuleProxy(t, map[string]string{
+			"github.com/operator-framework/operator-sdk/@v/list": "v1.38.0\nv1.39.1\n",
+		})
+		newFakeGitHubRawContent(t, map[string]string{
+			"openshift/api/release-4.17/go.mod":              openshiftAPIGoMod("v0.30.2"),
+			"openshift/api/release-4.18/go.mod":              openshiftAPIGoMod("v0.31.1"),
+			"operator-framework/operator-sdk/v1.38.0/go.mod": fullOperatorSdkGoMod("v0.30.3", "v0.18.5"),
+			"operator-framework/operator-sdk/v1.39.1/go.mod": fullOperatorSdkGoMod("v0.31.2", "v0.19.4"),
+		})
+		var out bytes.Buffer
+
+		err := PrintMatrix(&out, "4.17", "4.18", MatrixFormatCSV, GenerateOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, `OpenShift,Kubernetes,operator-sdk,controller-runtime,controller-tools,operator-framework/api,operator-registry
+4.17,v0.30.2,v1.38.0,v0.18.5,v0.16.5,v0.27.0,v1.49.0
+4.18,v0.31.1,v1.39.1,v0.19.4,v0.16.5,v0.27.0,v1.49.0
+`, out.String())
+	})
+
+	t.Run("Openshift version not in the matrix", func(t *testing.T) {
+		err := PrintMatrix(&bytes.Buffer{}, "4.17", "4.18", MatrixFormatTable, options)
+		require.EqualError(t, err, fmt.Sprintf("compatibility matrix %s has no entry for Openshift 4.17, run 'goupgrader matrix refresh --from=4.17' to add it", matrixPath))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := PrintMatrix(&bytes.Buffer{}, "4.18", "", "yaml", options)
+		require.EqualError(t, err, `unknown format "yaml": must be one of table, csv, json`)
+	})
+}
@@ -0,0 +1,455 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// writeGeneratedConfig saves the generated config to the given path, in the requested format or the one matching its
+// extension, or merges it into the config already there if requested.
+func writeGeneratedConfig(cfg *Config, configPath string, options GenerateOptions) error {
+	format, err := configFormat(configPath, options.Format)
+	if err != nil {
+		return err
+	}
+	if options.Merge {
+		return mergeConfigIntoFile(cfg, configPath, format)
+	}
+	return saveConfigToFile(cfg, configPath, format)
+}
+
+// mergeConfigIntoFile merges the generated config into the config file at the given path, which is created if it
+// doesn't exist yet, and logs what changed.
+func mergeConfigIntoFile(cfg *Config, configPath string, format ConfigFormat) error {
+	existing, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return saveConfigToFile(cfg, configPath, format)
+	} else if err != nil {
+		return err
+	}
+
+	merge := mergeConfig
+	if format != ConfigFormatYAML {
+		merge = func(existing []byte, cfg *Config) ([]byte, []string, error) {
+			return mergeConfigData(existing, cfg, configPath, format)
+		}
+	}
+	merged, changes, err := merge(existing, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to merge into %s: %w", configPath, err)
+	}
+
+	if len(changes) == 0 {
+		log.Info().Msgf("%s is already up to date", configPath)
+		return nil
+	}
+	for _, change := range changes {
+		log.Info().Msg(change)
+	}
+
+	if err := os.WriteFile(configPath, merged, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	log.Info().Msgf("config merged into %s", configPath)
+
+	return nil
+}
+
+// mergeConfig updates the existing YAML config with the dependencies of the generated config: the version, branch
+// and sourceBranch of the dependencies already in the existing config are updated in place, and the others are
+// appended. Everything else, including the other dependencies, their order and the comments, is preserved: the lines
+// of the config are edited in place (see configPatch), so that the lines left unchanged are kept byte for byte, unless
+// the config uses a flow style, in which case it is encoded again.
+// It returns the merged YAML config and a diff-like description of each change.
+func mergeConfig(existing []byte, cfg *Config) ([]byte, []string, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(existing, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	// an empty file has no document, nor lines to keep
+	empty := doc.Kind == 0
+	if empty {
+		doc = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil, nil, fmt.Errorf("line %d: the config must be a mapping", root.Line)
+	}
+
+	dependencies := mappingValue(root, "dependencies")
+	if dependencies != nil && dependencies.Kind != yamlv3.SequenceNode {
+		return nil, nil, fmt.Errorf("line %d: dependencies must be a list", dependencies.Line)
+	}
+
+	var patch *configPatch
+	if !empty {
+		patch = newConfigPatch(existing, root, dependencies)
+	}
+	if dependencies == nil {
+		dependencies = &yamlv3.Node{Kind: yamlv3.SequenceNode}
+		root.Content = append(root.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: "dependencies"}, dependencies)
+	}
+
+	var changes []string
+	for _, dep := range cfg.Dependencies {
+		entry := findDependencyNode(dependencies, dep.Package)
+		if entry == nil {
+			entry = &yamlv3.Node{}
+			if err := entry.Encode(dep); err != nil {
+				return nil, nil, err
+			}
+			if patch != nil {
+				if err := patch.appendEntry(dep); err != nil {
+					return nil, nil, err
+				}
+			}
+			dependencies.Content = append(dependencies.Content, entry)
+			changes = append(changes, fmt.Sprintf("+ %s: %s", dep.Package, describeTarget(dep)))
+			continue
+		}
+
+		set := func(key, value string) {
+			if patch != nil && !patch.setValue(entry, key, value) {
+				patch = nil
+			}
+			setMappingValue(entry, key, value)
+		}
+		before := dependencyFromNode(entry)
+		set("version", dep.Version)
+		set("branch", dep.Branch)
+		set("sourceBranch", dep.SourceBranch)
+		// the group of hand-maintained entries is kept unless generate puts them in one
+		if dep.Group != "" {
+			set("group", dep.Group)
+		}
+
+		if after := dependencyFromNode(entry); after != before {
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", dep.Package, describeTarget(before), describeTarget(after)))
+		}
+	}
+
+	if patch != nil {
+		return patch.bytes(), changes, nil
+	}
+
+	var out bytes.Buffer
+	encoder := yamlv3.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return out.Bytes(), changes, nil
+}
+
+// configPatch edits the lines of a YAML config during a merge, so that the lines the merge doesn't change are kept
+// byte for byte, whatever the indentation and quoting style of the config.
+type configPatch struct {
+	lines   []string
+	replace map[int]string       // new content of the line
+	remove  map[int]bool         // lines removed
+	insert  map[int][]string     // lines inserted after the line
+	ends    map[*yamlv3.Node]int // last line of the entries, before they are edited
+	end     int                  // line after which the new dependencies are appended
+	indent  string               // indentation of the dependencies sequence
+	entries []string             // lines of the new dependencies
+}
+
+// newConfigPatch returns a patch of the given config, or nil if the config is laid out in a way the patch doesn't
+// support: a flow style mapping or dependencies sequence, or an empty dependencies sequence.
+func newConfigPatch(existing []byte, root, dependencies *yamlv3.Node) *configPatch {
+	if root.Style&yamlv3.FlowStyle != 0 {
+		return nil
+	}
+
+	patch := &configPatch{
+		lines:   strings.Split(strings.TrimSuffix(string(existing), "\n"), "\n"),
+		replace: map[int]string{},
+		remove:  map[int]bool{},
+		insert:  map[int][]string{},
+		ends:    map[*yamlv3.Node]int{},
+	}
+	switch {
+	case dependencies == nil:
+		// like generate writes them
+		patch.end = len(patch.lines) - 1
+		patch.entries = []string{"dependencies:"}
+	case dependencies.Style&yamlv3.FlowStyle == 0 && len(dependencies.Content) > 0:
+		patch.end = lastLine(dependencies) - 1
+		patch.indent = strings.Repeat(" ", dependencies.Column-1)
+	default:
+		return nil
+	}
+
+	return patch
+}
+
+// setValue records the edit of setMappingValue on a dependency entry: the value of an existing key is replaced in
+// its own style, keeping any comment after it, an emptied key is removed, and a new key is added after the last line
+// of the entry. It returns false if the entry is laid out in a way the patch doesn't support.
+func (p *configPatch) setValue(entry *yamlv3.Node, key, value string) bool {
+	if entry.Style&yamlv3.FlowStyle != 0 {
+		return false
+	}
+	if _, found := p.ends[entry]; !found {
+		p.ends[entry] = lastLine(entry) - 1
+	}
+
+	for i := 0; i+1 < len(entry.Content); i += 2 {
+		keyNode, valueNode := entry.Content[i], entry.Content[i+1]
+		if keyNode.Value != key {
+			continue
+		}
+		// removing the first key would remove the dash of the entry
+		if valueNode.Kind != yamlv3.ScalarNode || valueNode.Line != keyNode.Line || value == "" && i == 0 {
+			return false
+		}
+
+		line := keyNode.Line - 1
+		switch {
+		case value == "":
+			p.remove[line] = true
+		case value != valueNode.Value:
+			runes := []rune(p.lines[line])
+			start := valueNode.Column - 1
+			end := scalarEnd(runes, start, valueNode.Style)
+			p.replace[line] = string(runes[:start]) + formatScalar(value, valueNode.Style) + string(runes[end:])
+		}
+		return true
+	}
+
+	if value != "" {
+		// new keys are quoted like the package of the entry
+		var style yamlv3.Style
+		if pkg := mappingValue(entry, "package"); pkg != nil {
+			style = pkg.Style
+		}
+		line := p.ends[entry]
+		p.insert[line] = append(p.insert[line],
+			fmt.Sprintf("%s%s: %s", strings.Repeat(" ", entry.Column-1), key, formatScalar(value, style)))
+	}
+	return true
+}
+
+// appendEntry records a new dependency, appended after the last one with the indentation of the sequence.
+func (p *configPatch) appendEntry(dep Dependency) error {
+	data, err := yaml.Marshal([]Dependency{dep})
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		p.entries = append(p.entries, p.indent+line)
+	}
+	return nil
+}
+
+// bytes returns the patched config.
+func (p *configPatch) bytes() []byte {
+	var out strings.Builder
+	for i, line := range p.lines {
+		if replaced, found := p.replace[i]; found {
+			line = replaced
+		}
+		if !p.remove[i] {
+			out.WriteString(line + "\n")
+		}
+		for _, inserted := range p.insert[i] {
+			out.WriteString(inserted + "\n")
+		}
+		if i == p.end {
+			for _, entry := range p.entries {
+				out.WriteString(entry + "\n")
+			}
+		}
+	}
+	return []byte(out.String())
+}
+
+// lastLine returns the last line of the node and its children.
+func lastLine(node *yamlv3.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		last = max(last, lastLine(child))
+	}
+	return last
+}
+
+// scalarEnd returns the index of the rune following the scalar starting at the given index of the line,
+// i.e. before any comment.
+func scalarEnd(line []rune, start int, style yamlv3.Style) int {
+	switch {
+	case style&yamlv3.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == '"' {
+				return i + 1
+			}
+		}
+	case style&yamlv3.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	default:
+		end := len(line)
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+				end = i
+				break
+			}
+		}
+		for end > start && (line[end-1] == ' ' || line[end-1] == '\t') {
+			end--
+		}
+		return end
+	}
+	return len(line)
+}
+
+// formatScalar formats the value as a YAML scalar in the given style, quoting a plain value that would not be read
+// back as the same string.
+func formatScalar(value string, style yamlv3.Style) string {
+	switch {
+	case style&yamlv3.DoubleQuotedStyle != 0:
+		return strconv.Quote(value)
+	case style&yamlv3.SingleQuotedStyle != 0:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	var read any
+	if err := yamlv3.Unmarshal([]byte(value), &read); err != nil || read != value {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// mergeConfigData merges the dependencies of the generated config into the existing JSON or TOML config the same
+// way as mergeConfig. These formats have no comments to preserve, so the existing config is decoded, updated and
+// encoded again.
+func mergeConfigData(existing []byte, cfg *Config, location string, format ConfigFormat) ([]byte, []string, error) {
+	config, err := decodeConfig(existing, location, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes []string
+	for _, dep := range cfg.Dependencies {
+		i := slices.IndexFunc(config.Dependencies, func(d Dependency) bool { return d.Package == dep.Package })
+		if i < 0 {
+			config.Dependencies = append(config.Dependencies, dep)
+			changes = append(changes, fmt.Sprintf("+ %s: %s", dep.Package, describeTarget(dep)))
+			continue
+		}
+
+		before := config.Dependencies[i]
+		after := before
+		after.Version, after.Branch, after.SourceBranch = dep.Version, dep.Branch, dep.SourceBranch
+		// the group of hand-maintained entries is kept unless generate puts them in one
+		if dep.Group != "" {
+			after.Group = dep.Group
+		}
+		if after != before {
+			config.Dependencies[i] = after
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", dep.Package, describeTarget(before), describeTarget(after)))
+		}
+	}
+
+	data, err := encodeConfig(config, format)
+	return data, changes, err
+}
+
+// describeTarget describes what a dependency is upgraded to, for the diff of a merge.
+func describeTarget(dep Dependency) string {
+	target := dep.Version
+	if dep.Branch != "" {
+		target = "branch " + dep.Branch
+	}
+	if dep.Group != "" {
+		target += fmt.Sprintf(" (group %s)", dep.Group)
+	}
+	return target
+}
+
+// findDependencyNode returns the entry of the dependencies sequence with the given package, if any.
+func findDependencyNode(dependencies *yamlv3.Node, pkg string) *yamlv3.Node {
+	for _, entry := range dependencies.Content {
+		if entry.Kind != yamlv3.MappingNode {
+			continue
+		}
+		if value := mappingValue(entry, "package"); value != nil && value.Value == pkg {
+			return entry
+		}
+	}
+	return nil
+}
+
+// dependencyFromNode reads the fields of a dependency entry.
+func dependencyFromNode(entry *yamlv3.Node) Dependency {
+	var dep Dependency
+	for field, value := range map[string]*string{
+		"package":      &dep.Package,
+		"version":      &dep.Version,
+		"branch":       &dep.Branch,
+		"group":        &dep.Group,
+		"sourceBranch": &dep.SourceBranch,
+	} {
+		if node := mappingValue(entry, field); node != nil {
+			*value = node.Value
+		}
+	}
+	return dep
+}
+
+// mappingValue returns the value of the given key of a mapping node, if any.
+func mappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of the given key of a mapping node, keeping the comments of an existing value,
+// or removes the key if the value is empty.
+func setMappingValue(mapping *yamlv3.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if value == "" {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+		mapping.Content[i+1].Kind = yamlv3.ScalarNode
+		mapping.Content[i+1].Tag = "!!str"
+		mapping.Content[i+1].Value = value
+		return
+	}
+
+	if value != "" {
+		mapping.Content = append(mapping.Content,
+			&yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key},
+			&yamlv3.Node{Kind: yamlv3.ScalarNode, Value: value})
+	}
+}
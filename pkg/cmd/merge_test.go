@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConfig(t *testing.T) {
+	existing := `# dependencies of the member operator
+go:
+  maxVersion: "1.23"
+dependencies:
+  # pinned until the e2e tests are fixed
+  - package: github.com/onsi/ginkgo/v2
+    version: v2.19.0
+  - package: sigs.k8s.io/controller-runtime
+    version: v0.18.5 # bumped by generate
+  - package: github.com/openshift/api
+    branch: release-4.17
+  - package: k8s.io/api
+    version: v0.31.1
+    group: kubernetes
+`
+	generated := &Config{Dependencies: []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+		{Package: "github.com/openshift/api", Version: "v0.0.0-20250301100000-0123456789ab", SourceBranch: "release-4.18"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+		{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+	}}
+
+	merged, changes, err := mergeConfig([]byte(existing), generated)
+
+	require.NoError(t, err)
+	assert.Equal(t, `# dependencies of the member operator
+go:
+  maxVersion: "1.23"
+dependencies:
+  # pinned until the e2e tests are fixed
+  - package: github.com/onsi/ginkgo/v2
+    version: v2.19.0
+  - package: sigs.k8s.io/controller-runtime
+    version: v0.19.4 # bumped by generate
+  - package: github.com/openshift/api
+    version: v0.0.0-20250301100000-0123456789ab
+    sourceBranch: release-4.18
+  - package: k8s.io/api
+    version: v0.31.1
+    group: kubernetes
+  - package: k8s.io/client-go
+    version: v0.31.1
+    group: kubernetes
+`, string(merged))
+	assert.Equal(t, []string{
+		"~ sigs.k8s.io/controller-runtime: v0.18.5 -> v0.19.4",
+		"~ github.com/openshift/api: branch release-4.17 -> v0.0.0-20250301100000-0123456789ab",
+		"+ k8s.io/client-go: v0.31.1 (group kubernetes)",
+	}, changes)
+
+	t.Run("yaml.v2 style config", func(t *testing.T) {
+		existing := `dependencies:
+- package: "sigs.k8s.io/controller-runtime"
+  version: "v0.18.5"    # bumped by generate
+
+- package: github.com/openshift/api
+  branch: 'release-4.17'
+  sourceBranch: master
+- package: github.com/onsi/ginkgo/v2
+  version: v2.19.0
+exclude:
+- github.com/onsi/gomega
+`
+
+		merged, changes, err := mergeConfig([]byte(existing), &Config{Dependencies: []Dependency{
+			{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+			{Package: "github.com/openshift/api", Branch: "release-4.18"},
+			{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, `dependencies:
+- package: "sigs.k8s.io/controller-runtime"
+  version: "v0.19.4"    # bumped by generate
+
+- package: github.com/openshift/api
+  branch: 'release-4.18'
+- package: github.com/onsi/ginkgo/v2
+  version: v2.19.0
+- package: k8s.io/api
+  version: v0.31.1
+  group: kubernetes
+exclude:
+- github.com/onsi/gomega
+`, string(merged))
+		assert.Equal(t, []string{
+			"~ sigs.k8s.io/controller-runtime: v0.18.5 -> v0.19.4",
+			"~ github.com/openshift/api: branch release-4.17 -> branch release-4.18",
+			"+ k8s.io/api: v0.31.1 (group kubernetes)",
+		}, changes)
+	})
+
+	t.Run("empty config", func(t *testing.T) {
+		merged, changes, err := mergeConfig(nil, &Config{Dependencies: []Dependency{{Package: "k8s.io/api", Version: "v0.31.1"}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "dependencies:\n  - package: k8s.io/api\n    version: v0.31.1\n", string(merged))
+		assert.Equal(t, []string{"+ k8s.io/api: v0.31.1"}, changes)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, _, err := mergeConfig([]byte("dependencies: sigs.k8s.io/controller-runtime\n"), generated)
+		require.EqualError(t, err, "line 1: dependencies must be a list")
+	})
+}
+
+func TestMergeConfigIntoFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	generated := &Config{Dependencies: []Dependency{{Package: "k8s.io/api", Version: "v0.31.1"}}}
+
+	// the config is created if it doesn't exist yet
+	require.NoError(t, writeGeneratedConfig(generated, configPath, GenerateOptions{Merge: true}))
+	content, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "dependencies:\n- package: k8s.io/api\n  version: v0.31.1\n", string(content))
+
+	// merging the same dependencies leaves the config untouched
+	require.NoError(t, os.WriteFile(configPath, []byte("dependencies:\n- package: k8s.io/api   # kept as is\n  version: v0.31.1\n"), 0600))
+	require.NoError(t, writeGeneratedConfig(generated, configPath, GenerateOptions{Merge: true}))
+	content, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "dependencies:\n- package: k8s.io/api   # kept as is\n  version: v0.31.1\n", string(content))
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/mod/module"
+)
+
+// validateRemote checks the dependencies against the module proxy and their git repositories: each module must exist,
+// each version must be published and each branch must exist. All problems are reported at once.
+func validateRemote(dependencies []Dependency) error {
+	var errs []error
+	modules := map[string]error{}
+	for _, dep := range dependencies {
+		if err := validateRemoteDependency(dep, modules); err != nil {
+			errs = append(errs, fmt.Errorf("dependency %s: %w", dep.Package, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateRemoteDependency checks a dependency against the module proxy and its git repository. modules caches
+// the outcome of the check of each module path, shared by the dependencies of the same module.
+func validateRemoteDependency(dep Dependency, modules map[string]error) error {
+	moduleErr, checked := modules[dep.Package]
+	if !checked {
+		moduleErr = checkModuleExists(dep.Package)
+		modules[dep.Package] = moduleErr
+	}
+	if moduleErr != nil {
+		return moduleErr
+	}
+
+	if dep.Version != "" {
+		return checkVersionPublished(dep.Package, dep.Version)
+	}
+	return checkBranchExists(dep.Package, dep.Branch)
+}
+
+// checkModuleExists checks that the module proxy knows the module.
+func checkModuleExists(modulePath string) error {
+	_, err := fetchFromModuleProxy(modulePath, "@latest")
+	if isNotFoundOnProxy(err) {
+		return errors.New("module not found on the module proxy")
+	}
+	return err
+}
+
+// checkVersionPublished checks that the module proxy serves the given version of the module.
+func checkVersionPublished(modulePath, version string) error {
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return fmt.Errorf("invalid version %s: %w", version, err)
+	}
+
+	_, err = fetchFromModuleProxy(modulePath, fmt.Sprintf("@v/%s.info", escapedVersion))
+	if isNotFoundOnProxy(err) {
+		return fmt.Errorf("version %s not found on the module proxy", version)
+	}
+	return err
+}
+
+// checkBranchExists checks that the git repository of the module has the given branch, the way branches are
+// resolved by getVersionWithCommitHashForBranch.
+func checkBranchExists(repo, branch string) error {
+	repoURL := fmt.Sprintf("https://%s.git", repo)
+	output, err := gitCommandFunc("", "ls-remote", "--heads", repoURL, "refs/heads/"+branch).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list the branches of %s: %w", repoURL, err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return fmt.Errorf("branch %s not found in %s", branch, repoURL)
+	}
+	return nil
+}
+
+// preflight checks the dependencies against the module proxy and their git repositories before anything is upgraded,
+// see validateRemote.
+func preflight(dependencies []Dependency) error {
+	log.Info().Msgf("checking %d dependencies against the module proxy and their git repositories", len(dependencies))
+	if err := validateRemote(dependencies); err != nil {
+		return fmt.Errorf("preflight check failed:\n%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemoteBranches replaces git ls-remote by a lookup in the given branches, keyed by "<repo-url> <branch>"
+func fakeRemoteBranches(t *testing.T, branches ...string) *[]string {
+	t.Helper()
+	origGitCommandFunc := gitCommandFunc
+	t.Cleanup(func() { gitCommandFunc = origGitCommandFunc })
+
+	var commands []string
+	gitCommandFunc = func(_ string, arg ...string) commandExecutor {
+		commands = append(commands, strings.Join(arg, " "))
+		for _, branch := range branches {
+			if len(arg) == 4 && arg[0] == "ls-remote" && strings.Join(arg[2:], " ") == branch {
+				return &MockCommandExecutor{Outcome: "0123456789abcdef0123456789abcdef01234567\t" + arg[3] + "\n"}
+			}
+		}
+		return &MockCommandExecutor{}
+	}
+	return &commands
+}
+
+func TestValidateRemote(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"sigs.k8s.io/controller-runtime/@latest":           `{"Version":"v0.20.0"}`,
+		"sigs.k8s.io/controller-runtime/@v/v0.19.3.info":   `{"Version":"v0.19.3"}`,
+		"github.com/openshift/api/@latest":                 `{"Version":"v0.0.0-20250301100000-0123456789ab"}`,
+		"github.com/!burnt!sushi/toml/@latest":             `{"Version":"v1.4.0"}`,
+		"github.com/!burnt!sushi/toml/@v/v1.4.0.info":      `{"Version":"v1.4.0"}`,
+		"k8s.io/api/@latest":                               `{"Version":"v0.32.0"}`,
+		"k8s.io/api/@v/v0.31.1.info":                       `{"Version":"v0.31.1"}`,
+		"github.com/onsi/ginkgo/v2/@latest":                `{"Version":"v2.22.0"}`,
+		"github.com/onsi/ginkgo/v2/@v/v2.20.0-rc.1.info":   `{"Version":"v2.20.0-rc.1"}`,
+		"github.com/openshift/library-go/@latest":          `{"Version":"v0.0.0-20250301100000-0123456789ab"}`,
+		"github.com/operator-framework/api/@v/v0.27.0.mod": "module github.com/operator-framework/api\n",
+	})
+	commands := fakeRemoteBranches(t, "https://github.com/openshift/api.git refs/heads/release-4.18")
+
+	t.Run("valid config", func(t *testing.T) {
+		err := validateRemote([]Dependency{
+			{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+			{Package: "github.com/openshift/api", Branch: "release-4.18"},
+			{Package: "github.com/BurntSushi/toml", Version: "v1.4.0"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ls-remote --heads https://github.com/openshift/api.git refs/heads/release-4.18"}, *commands)
+	})
+
+	t.Run("all problems are reported", func(t *testing.T) {
+		err := validateRemote([]Dependency{
+			{Package: "sigs.k8s.io/controler-runtime", Version: "v0.19.3"},
+			{Package: "k8s.io/api", Version: "v0.31.1"},
+			{Package: "github.com/onsi/ginkgo/v2", Version: "v2.20.0"},
+			{Package: "github.com/openshift/library-go", Branch: "release-4.81"},
+			{Package: "github.com/operator-framework/api", Version: "v0.27.0"},
+		})
+
+		require.EqualError(t, err, `dependency sigs.k8s.io/controler-runtime: module not found on the module proxy
+dependency github.com/onsi/ginkgo/v2: version v2.20.0 not found on the module proxy
+dependency github.com/openshift/library-go: branch release-4.81 not found in https://github.com/openshift/library-go.git
+dependency github.com/operator-framework/api: module not found on the module proxy`)
+	})
+}
+
+func TestUpgradePreflight(t *testing.T) {
+	newFakeModuleProxy(t, map[string]string{
+		"sigs.k8s.io/controller-runtime/@latest": `{"Version":"v0.20.0"}`,
+	})
+	fakeRemoteBranches(t)
+	origGoCommandFunc := goCommandFunc
+	t.Cleanup(func() { goCommandFunc = origGoCommandFunc })
+	var commands []string
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		commands = append(commands, strings.Join(arg, " "))
+		return &MockCommandExecutor{}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.99"
+  - package: "github.com/openshift/api"
+    branch: "release-4.18"
+`), 0600))
+
+	_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{Preflight: true})
+
+	require.EqualError(t, err, `preflight check failed:
+dependency sigs.k8s.io/controller-runtime: version v0.19.99 not found on the module proxy
+dependency github.com/openshift/api: module not found on the module proxy`)
+	assert.Empty(t, commands)
+
+	t.Run("skipped with locked versions", func(t *testing.T) {
+		_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{Preflight: true, Locked: true})
+
+		// the missing lockfile is reported, not the missing versions
+		require.ErrorContains(t, err, "lockfile")
+	})
+
+	t.Run("on by default", func(t *testing.T) {
+		assert.Equal(t, "true", NewUpgrade().Flags().Lookup("preflight").DefValue)
+	})
+}
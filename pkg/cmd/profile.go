@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultProfile is the profile used by generate when no --profile is given
+var defaultProfile = Profile{
+	Dependencies: []ProfileDependency{
+		{Package: "sigs.k8s.io/controller-runtime", Track: TrackVersion},
+		{Package: "github.com/operator-framework/api", Track: TrackVersion},
+		{Package: "github.com/operator-framework/operator-registry", Track: TrackVersion},
+		{Package: "sigs.k8s.io/controller-tools", Track: TrackVersion},
+		{Package: "github.com/openshift/api", Track: TrackBranch},
+		{Package: "github.com/openshift/library-go", Track: TrackBranch},
+	},
+}
+
+// loadProfile parses the YAML profile at the given path, or returns the default profile if the path is empty.
+func loadProfile(profilePath string) (*Profile, error) {
+	if profilePath == "" {
+		return &defaultProfile, nil
+	}
+
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", profilePath, err)
+	}
+
+	if len(profile.Dependencies) == 0 {
+		return nil, fmt.Errorf("profile %s: no dependencies defined", profilePath)
+	}
+
+	for _, dep := range profile.Dependencies {
+		if err := validateProfileDependency(dep); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profilePath, err)
+		}
+	}
+
+	return &profile, nil
+}
+
+// validateProfileDependency checks if a profile dependency has a package and a valid tracking mode.
+func validateProfileDependency(dependency ProfileDependency) error {
+	if dependency.Package == "" {
+		return fmt.Errorf("dependency without package")
+	}
+
+	if dependency.Track != TrackVersion && dependency.Track != TrackBranch {
+		return fmt.Errorf("dependency %s: track must be either %s or %s", dependency.Package, TrackVersion, TrackBranch)
+	}
+
+	return nil
+}
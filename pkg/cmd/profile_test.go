@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProfile(t *testing.T) {
+	t.Run("default profile", func(t *testing.T) {
+		profile, err := loadProfile("")
+		require.NoError(t, err)
+		assert.Equal(t, &defaultProfile, profile)
+	})
+
+	tests := []struct {
+		name            string
+		profile         string
+		expectedProfile *Profile
+		expectedError   string
+	}{
+		{
+			name: "valid profile",
+			profile: `dependencies:
+  - package: k8s.io/client-go
+    track: version
+  - package: github.com/openshift/api
+    track: branch`,
+			expectedProfile: &Profile{Dependencies: []ProfileDependency{
+				{Package: "k8s.io/client-go", Track: TrackVersion},
+				{Package: "github.com/openshift/api", Track: TrackBranch},
+			}},
+		},
+		{
+			name: "invalid track",
+			profile: `dependencies:
+  - package: k8s.io/client-go
+    track: tag`,
+			expectedError: "dependency k8s.io/client-go: track must be either version or branch",
+		},
+		{
+			name: "missing package",
+			profile: `dependencies:
+  - track: version`,
+			expectedError: "dependency without package",
+		},
+		{
+			name:          "no dependencies",
+			profile:       `dependencies: []`,
+			expectedError: "no dependencies defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profilePath := filepath.Join(t.TempDir(), "profile.yaml")
+			require.NoError(t, os.WriteFile(profilePath, []byte(test.profile), 0600))
+
+			profile, err := loadProfile(profilePath)
+
+			if test.expectedError != "" {
+				require.EqualError(t, err, "profile "+profilePath+": "+test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedProfile, profile)
+		})
+	}
+
+	t.Run("non-existent profile path", func(t *testing.T) {
+		_, err := loadProfile("/nonexistent/profile.yaml")
+		require.Error(t, err)
+	})
+}
+
+func TestBuildDependencyConfig(t *testing.T) {
+	newFakeGitHubRawContent(t, map[string]string{
+		"operator-framework/operator-sdk/v1.39.2/go.mod": `module github.com/operator-framework/operator-sdk
+
+require (
+	github.com/onsi/ginkgo/v2 v2.20.2
+	k8s.io/client-go v0.31.2
+	sigs.k8s.io/controller-runtime v0.19.4
+)
+`,
+	})
+
+	profile := &Profile{Dependencies: []ProfileDependency{
+		{Package: "sigs.k8s.io/controller-runtime", Track: TrackVersion},
+		{Package: "k8s.io/client-go", Track: TrackVersion},
+		{Package: "github.com/onsi/ginkgo/v2", Track: TrackVersion},
+		{Package: "github.com/openshift/api", Track: TrackBranch},
+	}}
+
+	config, err := buildDependencyConfig("operator-framework/operator-sdk", "v1.39.2", "4.18", profile)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.4"},
+		{Package: "k8s.io/client-go", Version: "v0.31.2"},
+		{Package: "github.com/onsi/ginkgo/v2", Version: "v2.20.2"},
+		{Package: "github.com/openshift/api", Branch: "release-4.18"},
+	}, config.Dependencies)
+
+	t.Run("package not required by the operator-sdk", func(t *testing.T) {
+		_, err := buildDependencyConfig("operator-framework/operator-sdk", "v1.39.2", "4.18", &Profile{
+			Dependencies: []ProfileDependency{{Package: "github.com/onsi/gomega", Track: TrackVersion}},
+		})
+		require.EqualError(t, err, "failed to get version for github.com/onsi/gomega: github.com/onsi/gomega not found in go.mod")
+	})
+}
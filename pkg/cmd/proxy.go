@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// moduleProxyURL is the Go module proxy used to look up published module metadata
+var moduleProxyURL = "https://proxy.golang.org"
+
+// proxyStatusError is returned when the module proxy answers with another status than 200
+type proxyStatusError struct {
+	StatusCode int
+	ModulePath string
+	Endpoint   string
+}
+
+func (e *proxyStatusError) Error() string {
+	return fmt.Sprintf("module proxy returned status %d for %s/%s", e.StatusCode, e.ModulePath, e.Endpoint)
+}
+
+// isNotFoundOnProxy returns true if the error is the module proxy not knowing the requested module or version.
+func isNotFoundOnProxy(err error) bool {
+	var statusErr *proxyStatusError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone)
+}
+
+// fetchFromModuleProxy requests the given endpoint (e.g. "@v/list") of a module from the module proxy.
+func fetchFromModuleProxy(modulePath, endpoint string) ([]byte, error) {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module path %s: %w", modulePath, err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(moduleProxyURL, "/"), escapedPath, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query module proxy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &proxyStatusError{StatusCode: resp.StatusCode, ModulePath: modulePath, Endpoint: endpoint}
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// listModuleVersions returns the versions of the given module known to the module proxy, in no particular order.
+func listModuleVersions(modulePath string) ([]string, error) {
+	data, err := fetchFromModuleProxy(modulePath, "@v/list")
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(data)), nil
+}
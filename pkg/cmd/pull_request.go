@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/rsoaresd/goupgrader/pkg/forge"
+)
+
+// forgeTokenEnvVars maps each supported forge to the environment variable holding its API token
+var forgeTokenEnvVars = map[string]string{
+	"github": "GITHUB_TOKEN",
+	"gitlab": "GITLAB_TOKEN",
+}
+
+// validatePullRequestOptions checks that a pull request can be opened with the given options
+// before anything is changed in the project.
+func validatePullRequestOptions(options UpgradeOptions) error {
+	if options.PullRequest == nil {
+		return nil
+	}
+
+	if options.GitBranch == "" {
+		return fmt.Errorf("opening a pull request requires --git-branch")
+	}
+
+	tokenEnvVar, found := forgeTokenEnvVars[options.PullRequest.Forge]
+	if !found {
+		return fmt.Errorf("unsupported forge %q: must be one of github, gitlab", options.PullRequest.Forge)
+	}
+
+	if os.Getenv(tokenEnvVar) == "" {
+		return fmt.Errorf("opening a pull request on %s requires the %s environment variable", options.PullRequest.Forge, tokenEnvVar)
+	}
+
+	return nil
+}
+
+// openPullRequest pushes the branch with the committed upgrades and opens a pull request from it,
+// using the Markdown report as body. If a pull request from the same branch is already open, it is updated.
+func openPullRequest(projectPath, branch, base string, report *Report, options PullRequestOptions) error {
+	repository := options.Repository
+	if repository == "" {
+		var err error
+		if repository, err = repositoryFromRemote(projectPath, options.Remote); err != nil {
+			return err
+		}
+	}
+
+	if err := pushBranch(projectPath, options.Remote, branch); err != nil {
+		return err
+	}
+
+	client, err := forge.New(options.Forge, options.URL, os.Getenv(forgeTokenEnvVars[options.Forge]))
+	if err != nil {
+		return err
+	}
+
+	pr, err := client.CreateOrUpdatePullRequest(forge.PullRequestOptions{
+		Repository: repository,
+		Head:       branch,
+		Base:       base,
+		Title:      strings.SplitN(commitMessage(report.Results), "\n", 2)[0],
+		Body:       report.Markdown(),
+		Labels:     options.Labels,
+		Reviewers:  options.Reviewers,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening pull request: %w", err)
+	}
+
+	log.Info().Msgf("pull request #%d: %s", pr.Number, pr.URL)
+
+	return nil
+}
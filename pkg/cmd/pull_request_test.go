@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePullRequestOptions(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITLAB_TOKEN", "")
+
+	tests := []struct {
+		name     string
+		options  UpgradeOptions
+		expected string
+	}{
+		{
+			name:     "No pull request",
+			options:  UpgradeOptions{},
+			expected: "",
+		},
+		{
+			name:     "Valid pull request",
+			options:  UpgradeOptions{GitBranch: "upgrade-deps", PullRequest: &PullRequestOptions{Forge: "github"}},
+			expected: "",
+		},
+		{
+			name:     "Invalid: no git branch",
+			options:  UpgradeOptions{PullRequest: &PullRequestOptions{Forge: "github"}},
+			expected: "opening a pull request requires --git-branch",
+		},
+		{
+			name:     "Invalid: unsupported forge",
+			options:  UpgradeOptions{GitBranch: "upgrade-deps", PullRequest: &PullRequestOptions{Forge: "gitea"}},
+			expected: "unsupported forge \"gitea\": must be one of github, gitlab",
+		},
+		{
+			name:     "Invalid: missing token",
+			options:  UpgradeOptions{GitBranch: "upgrade-deps", PullRequest: &PullRequestOptions{Forge: "gitlab"}},
+			expected: "opening a pull request on gitlab requires the GITLAB_TOKEN environment variable",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validatePullRequestOptions(test.options)
+
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestUpgradeOpenPullRequest(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	origGoCommandFunc := goCommandFunc
+	origGitCommandFunc := gitCommandFunc
+	defer func() {
+		goCommandFunc = origGoCommandFunc
+		gitCommandFunc = origGitCommandFunc
+	}()
+
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+
+	var gitCommands []string
+	gitCommandFunc = func(_ string, arg ...string) commandExecutor {
+		gitCommands = append(gitCommands, strings.Join(arg, " "))
+		switch arg[0] {
+		case "rev-parse":
+			return &MockCommandExecutor{Outcome: "master\n"}
+		case "remote":
+			return &MockCommandExecutor{Outcome: "git@github.com:codeready-toolchain/member-operator.git\n"}
+		default:
+			return &MockCommandExecutor{}
+		}
+	}
+
+	var created map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `[]`)
+		case http.MethodPost:
+			assert.Equal(t, "/repos/codeready-toolchain/member-operator/pulls", r.URL.Path)
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			fmt.Fprint(w, `{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}`)
+		}
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"`), 0600))
+
+	cmd := NewUpgrade()
+	cmd.SetArgs([]string{
+		fmt.Sprintf("--config=%s", configPath),
+		"--project=/path/to/project",
+		"--git-branch=upgrade-deps",
+		"--open-pr",
+		"--preflight=false",
+		fmt.Sprintf("--forge-url=%s", server.URL),
+	})
+
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, []string{
+		"status --porcelain",
+		"rev-parse --abbrev-ref HEAD",
+		"for-each-ref --format=%(upstream:short) refs/heads/upgrade-deps",
+		"checkout -b upgrade-deps",
+		"add --all .",
+		"commit --quiet --message Upgrade sigs.k8s.io/controller-runtime to v0.19.3\n\n" +
+			"- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3\n",
+		"remote get-url origin",
+		"push --force-with-lease --set-upstream origin upgrade-deps",
+	}, gitCommands)
+	assert.Equal(t, map[string]string{
+		"title": "Upgrade sigs.k8s.io/controller-runtime to v0.19.3",
+		"body": "## Dependency upgrades\n\n" +
+			"| Package | From | To | Status |\n" +
+			"|---|---|---|---|\n" +
+			"| `sigs.k8s.io/controller-runtime` | v0.18.4 | v0.19.3 | upgraded |\n",
+		"head": "upgrade-deps",
+		"base": "master",
+	}, created)
+}
+
+func TestUpgradeUpdatePullRequest(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	origGoCommandFunc := goCommandFunc
+	origGitCommandFunc := gitCommandFunc
+	defer func() {
+		goCommandFunc = origGoCommandFunc
+		gitCommandFunc = origGitCommandFunc
+	}()
+
+	goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+
+	// the branch and the pull request of a previous run both exist
+	var gitCommands []string
+	gitCommandFunc = func(_ string, arg ...string) commandExecutor {
+		gitCommands = append(gitCommands, strings.Join(arg, " "))
+		switch arg[0] {
+		case "remote":
+			return &MockCommandExecutor{Outcome: "https://github.com/codeready-toolchain/member-operator.git\n"}
+		case "for-each-ref":
+			return &MockCommandExecutor{Outcome: "origin/upgrade-deps\n"}
+		}
+		return &MockCommandExecutor{}
+	}
+
+	var updated map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			assert.Equal(t, "codeready-toolchain:upgrade-deps", r.URL.Query().Get("head"))
+			fmt.Fprint(w, `[{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}]`)
+		case http.MethodPatch:
+			assert.Equal(t, "/repos/codeready-toolchain/member-operator/pulls/42", r.URL.Path)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+			fmt.Fprint(w, `{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"`), 0600))
+
+	_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{
+		GitBranch:   "upgrade-deps",
+		PullRequest: &PullRequestOptions{Forge: "github", URL: server.URL, Remote: "origin", Base: "main"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"status --porcelain",
+		"for-each-ref --format=%(upstream:short) refs/heads/upgrade-deps",
+		"checkout -B upgrade-deps",
+		"add --all .",
+		"commit --quiet --message Upgrade sigs.k8s.io/controller-runtime to v0.19.3\n\n" +
+			"- sigs.k8s.io/controller-runtime: v0.18.4 → v0.19.3\n",
+		"remote get-url origin",
+		"push --force-with-lease --set-upstream origin upgrade-deps",
+	}, gitCommands)
+	assert.Equal(t, map[string]string{
+		"title": "Upgrade sigs.k8s.io/controller-runtime to v0.19.3",
+		"body": "## Dependency upgrades\n\n" +
+			"| Package | From | To | Status |\n" +
+			"|---|---|---|---|\n" +
+			"| `sigs.k8s.io/controller-runtime` | v0.18.4 | v0.19.3 | upgraded |\n",
+	}, updated)
+}
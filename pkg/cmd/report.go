@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// hasUpgrades returns true if at least one dependency was upgraded or added.
+func (r *Report) hasUpgrades() bool {
+	return hasUpgrades(r.Results)
+}
+
+func hasUpgrades(results []UpgradeResult) bool {
+	for _, result := range results {
+		if result.Status == UpgradeStatusUpgraded || result.Status == UpgradeStatusAdded {
+			return true
+		}
+	}
+	return false
+}
+
+// logReport prints a summary of the upgrade run.
+func logReport(report *Report) {
+	for _, result := range report.Results {
+		switch result.Status {
+		case UpgradeStatusUpgraded:
+			log.Info().Msgf("%s: %s -> %s", result.Package, result.From, result.To)
+		case UpgradeStatusUpToDate:
+			log.Info().Msgf("%s: %s (%s)", result.Package, result.From, result.Status)
+		case UpgradeStatusAdded:
+			log.Info().Msgf("%s: added at %s", result.Package, result.To)
+		case UpgradeStatusSkipped:
+			log.Info().Msgf("%s: %s (%s)", result.Package, result.To, result.Status)
+		default:
+			log.Info().Msgf("%s: %s", result.Package, result.Status)
+		}
+	}
+
+	if report.Go != nil {
+		log.Info().Msgf("go directive: %s -> %s, toolchain: %s -> %s",
+			orNone(report.Go.FromGo), orNone(report.Go.ToGo), orNone(report.Go.FromToolchain), orNone(report.Go.ToToolchain))
+	}
+
+	if report.Vendor != nil {
+		log.Info().Msgf("vendor: %d module(s) added, %d updated, %d removed",
+			len(report.Vendor.Added), len(report.Vendor.Updated), len(report.Vendor.Removed))
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// Markdown renders the report as Markdown, suitable for the body of a pull request.
+func (r *Report) Markdown() string {
+	var md strings.Builder
+
+	md.WriteString("## Dependency upgrades\n\n")
+	md.WriteString("| Package | From | To | Status |\n")
+	md.WriteString("|---|---|---|---|\n")
+	for _, result := range r.Results {
+		fmt.Fprintf(&md, "| `%s` | %s | %s | %s |\n", result.Package, orDash(result.From), orDash(result.To), result.Status)
+	}
+
+	if r.Go != nil {
+		md.WriteString("\n## Go directive\n\n")
+		fmt.Fprintf(&md, "- go: %s → %s\n", orNone(r.Go.FromGo), orNone(r.Go.ToGo))
+		fmt.Fprintf(&md, "- toolchain: %s → %s\n", orNone(r.Go.FromToolchain), orNone(r.Go.ToToolchain))
+	}
+
+	if r.Vendor != nil {
+		md.WriteString("\n## Vendor directory\n\n")
+		fmt.Fprintf(&md, "%d module(s) added, %d updated, %d removed.\n", len(r.Vendor.Added), len(r.Vendor.Updated), len(r.Vendor.Removed))
+		writeModuleList(&md, "Added", r.Vendor.Added)
+		writeModuleList(&md, "Updated", r.Vendor.Updated)
+		writeModuleList(&md, "Removed", r.Vendor.Removed)
+	}
+
+	return md.String()
+}
+
+func writeModuleList(md *strings.Builder, title string, modules []string) {
+	if len(modules) == 0 {
+		return
+	}
+
+	fmt.Fprintf(md, "\n<details><summary>%s</summary>\n\n", title)
+	for _, module := range modules {
+		fmt.Fprintf(md, "- `%s`\n", module)
+	}
+	md.WriteString("\n</details>\n")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
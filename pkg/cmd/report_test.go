@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportMarkdown(t *testing.T) {
+	report := &Report{
+		Results: []UpgradeResult{
+			{Package: "sigs.k8s.io/controller-runtime", From: "v0.18.4", To: "v0.19.3", Status: UpgradeStatusUpgraded},
+			{Package: "sigs.k8s.io/controller-tools", From: "v0.16.5", To: "v0.16.5", Status: UpgradeStatusUpToDate},
+			{Package: "github.com/openshift/library-go", To: "v0.0.0-20250410062700-d6c84c55a124", Status: UpgradeStatusNotFound},
+		},
+		Go: &GoDirectiveChange{FromGo: "1.22.0", ToGo: "1.23.0", ToToolchain: "go1.23.4"},
+		Vendor: &VendorStats{
+			Added:   []string{"github.com/fxamacker/cbor/v2"},
+			Updated: []string{"sigs.k8s.io/controller-runtime"},
+		},
+	}
+
+	assert.Equal(t, "## Dependency upgrades\n\n"+
+		"| Package | From | To | Status |\n"+
+		"|---|---|---|---|\n"+
+		"| `sigs.k8s.io/controller-runtime` | v0.18.4 | v0.19.3 | upgraded |\n"+
+		"| `sigs.k8s.io/controller-tools` | v0.16.5 | v0.16.5 | up-to-date |\n"+
+		"| `github.com/openshift/library-go` | - | v0.0.0-20250410062700-d6c84c55a124 | not found in go.mod |\n"+
+		"\n## Go directive\n\n"+
+		"- go: 1.22.0 → 1.23.0\n"+
+		"- toolchain: none → go1.23.4\n"+
+		"\n## Vendor directory\n\n"+
+		"1 module(s) added, 1 updated, 0 removed.\n"+
+		"\n<details><summary>Added</summary>\n\n"+
+		"- `github.com/fxamacker/cbor/v2`\n"+
+		"\n</details>\n"+
+		"\n<details><summary>Updated</summary>\n\n"+
+		"- `sigs.k8s.io/controller-runtime`\n"+
+		"\n</details>\n", report.Markdown())
+}
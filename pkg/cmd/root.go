@@ -22,7 +22,7 @@ accordingly using the generated configuration.`,
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -30,4 +30,7 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(NewGenerateConfigForOpenshiftDependencies())
 	rootCmd.AddCommand(NewUpgrade())
+	rootCmd.AddCommand(NewMatrix())
+	rootCmd.AddCommand(NewLock())
+	rootCmd.AddCommand(NewConfig())
 }
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// requiredGoVersion returns the version of the go directive declared by the given module version, looked up with
+// 'go list' in the project so that the Go environment of the user (GOPROXY, GOPRIVATE, etc.) applies.
+// An empty string is returned for modules that do not declare one.
+func requiredGoVersion(projectPath, packageName, targetVersion string) (string, error) {
+	output, err := goCommandFunc(false, projectPath, "list", "-m", "-json", fmt.Sprintf("%s@%s", packageName, targetVersion)).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect go version required by %s@%s: failed to run 'go list': %w", packageName, targetVersion, err)
+	}
+
+	var info struct {
+		GoVersion string
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", fmt.Errorf("failed to detect go version required by %s@%s: failed to parse 'go list' JSON: %w", packageName, targetVersion, err)
+	}
+
+	return info.GoVersion, nil
+}
+
+// compareGoVersions compares two go directive versions (e.g. "1.22" and "1.22.3")
+// and returns -1, 0 or +1 like strings.Compare.
+func compareGoVersions(v1, v2 string) int {
+	return version.Compare("go"+v1, "go"+v2)
+}
+
+// prepareGoDirective makes sure the go directive of the project can accommodate the given
+// module version before it is upgraded. If the module requires a newer go version than the
+// configured maximum, the upgrade is refused. If the module requires a newer go version than
+// the project currently declares, the change is either applied explicitly with 'go mod edit'
+// or reported, depending on the go section of the config.
+func prepareGoDirective(projectPath string, goConfig *GoConfig, packageName, targetVersion string) error {
+	required, err := requiredGoVersion(projectPath, packageName, targetVersion)
+	if err != nil || required == "" {
+		return err
+	}
+
+	if goConfig.MaxVersion != "" && compareGoVersions(required, goConfig.MaxVersion) > 0 {
+		return fmt.Errorf("dependency %s@%s requires go %s, which exceeds the maximum go version %s",
+			packageName, targetVersion, required, goConfig.MaxVersion)
+	}
+
+	current, _, err := getGoDirective(projectPath)
+	if err != nil {
+		return err
+	}
+
+	if current != "" && compareGoVersions(required, current) <= 0 {
+		return nil
+	}
+
+	if !goConfig.Apply {
+		log.Warn().Msgf("%s@%s requires go %s: the go directive of the project (go %s) will be bumped by go get",
+			packageName, targetVersion, required, current)
+		return nil
+	}
+
+	log.Info().Msgf("%s@%s requires go %s: updating go directive from %s to %s...",
+		packageName, targetVersion, required, current, required)
+
+	args := []string{"mod", "edit", fmt.Sprintf("-go=%s", required)}
+	if goConfig.Toolchain != "" {
+		args = append(args, fmt.Sprintf("-toolchain=%s", goConfig.Toolchain))
+	}
+	if err := goCommandFunc(true, projectPath, args...).Run(); err != nil {
+		return fmt.Errorf("error updating go directive to %s: %w", required, err)
+	}
+
+	return nil
+}
+
+// checkGoDirective returns an error if the go directive of the project is above the maximum go version once the
+// given dependencies are upgraded, as the modules required by the upgraded ones may declare a newer go version
+// than the upgraded ones themselves.
+func checkGoDirective(projectPath string, goConfig *GoConfig, upgraded string) error {
+	current, _, err := getGoDirective(projectPath)
+	if err != nil {
+		return err
+	}
+
+	if current != "" && compareGoVersions(current, goConfig.MaxVersion) > 0 {
+		return fmt.Errorf("upgrading %s raised the go directive to %s, which exceeds the maximum go version %s",
+			upgraded, current, goConfig.MaxVersion)
+	}
+
+	return nil
+}
+
+// saveModFiles returns a function restoring go.mod and go.sum of the project to their current content, removing
+// them if they don't exist yet, so that an upgrade refused once 'go get' ran leaves the project as it was.
+func saveModFiles(projectPath string) (func() error, error) {
+	saved := map[string][]byte{}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		path := filepath.Join(projectPath, name)
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		saved[path] = data
+	}
+
+	return func() error {
+		for path, data := range saved {
+			var err error
+			if data == nil {
+				err = os.Remove(path)
+			} else {
+				err = os.WriteFile(path, data, 0644)
+			}
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to restore %s: %w", path, err)
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeModuleProxy starts a module proxy serving the given files, keyed by their path
+// relative to the proxy root (e.g. "sigs.k8s.io/controller-runtime/@v/v0.19.3.mod").
+func newFakeModuleProxy(t *testing.T, files map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, found := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	t.Cleanup(server.Close)
+
+	origModuleProxyURL := moduleProxyURL
+	moduleProxyURL = server.URL
+	t.Cleanup(func() { moduleProxyURL = origModuleProxyURL })
+}
+
+func TestRequiredGoVersion(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	var commands []string
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		commands = append(commands, strings.Join(arg, " "))
+		switch arg[len(arg)-1] {
+		case "sigs.k8s.io/controller-runtime@v0.19.3":
+			return &MockCommandExecutor{Outcome: `{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.19.3","GoVersion":"1.22.0"}`}
+		case "github.com/BurntSushi/toml@v0.3.1":
+			return &MockCommandExecutor{Outcome: `{"Path":"github.com/BurntSushi/toml","Version":"v0.3.1"}`}
+		default:
+			return &MockCommandExecutor{OutputErr: fmt.Errorf("exit status 1")}
+		}
+	}
+
+	t.Run("go directive declared", func(t *testing.T) {
+		required, err := requiredGoVersion("/path/to/project", "sigs.k8s.io/controller-runtime", "v0.19.3")
+		require.NoError(t, err)
+		assert.Equal(t, "1.22.0", required)
+		assert.Equal(t, "list -m -json sigs.k8s.io/controller-runtime@v0.19.3", commands[len(commands)-1])
+	})
+
+	t.Run("no go directive", func(t *testing.T) {
+		required, err := requiredGoVersion("/path/to/project", "github.com/BurntSushi/toml", "v0.3.1")
+		require.NoError(t, err)
+		assert.Empty(t, required)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := requiredGoVersion("/path/to/project", "sigs.k8s.io/controller-runtime", "v0.99.0")
+		require.EqualError(t, err, "failed to detect go version required by sigs.k8s.io/controller-runtime@v0.99.0: "+
+			"failed to run 'go list': exit status 1")
+	})
+}
+
+func TestPrepareGoDirective(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	tests := []struct {
+		name             string
+		goConfig         GoConfig
+		projectGo        string
+		expectedCommands []string
+		expectedError    string
+	}{
+		{
+			name:      "apply go directive and toolchain",
+			goConfig:  GoConfig{Apply: true, Toolchain: "go1.23.4"},
+			projectGo: "1.22.0",
+			expectedCommands: []string{"list -m -json sigs.k8s.io/controller-runtime@v0.20.0", "mod edit -json",
+				"mod edit -go=1.23.0 -toolchain=go1.23.4"},
+		},
+		{
+			name:             "report only",
+			goConfig:         GoConfig{},
+			projectGo:        "1.22.0",
+			expectedCommands: []string{"list -m -json sigs.k8s.io/controller-runtime@v0.20.0", "mod edit -json"},
+		},
+		{
+			name:             "project already on required go version",
+			goConfig:         GoConfig{Apply: true},
+			projectGo:        "1.23.2",
+			expectedCommands: []string{"list -m -json sigs.k8s.io/controller-runtime@v0.20.0", "mod edit -json"},
+		},
+		{
+			name:          "required go version exceeds the maximum",
+			goConfig:      GoConfig{Apply: true, MaxVersion: "1.22.7"},
+			projectGo:     "1.22.0",
+			expectedError: "dependency sigs.k8s.io/controller-runtime@v0.20.0 requires go 1.23.0, which exceeds the maximum go version 1.22.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []string
+			goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+				commands = append(commands, strings.Join(arg, " "))
+				if arg[0] == "list" {
+					return &MockCommandExecutor{Outcome: `{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.20.0","GoVersion":"1.23.0"}`}
+				}
+				return &MockCommandExecutor{Outcome: fmt.Sprintf(`{"Go":%q}`, tt.projectGo)}
+			}
+
+			err := prepareGoDirective("/path/to/project", &tt.goConfig, "sigs.k8s.io/controller-runtime", "v0.20.0")
+
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommands, commands)
+		})
+	}
+}
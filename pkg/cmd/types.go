@@ -4,23 +4,159 @@ import "time"
 
 // Config struct to hold the list of dependencies
 type Config struct {
-	Dependencies []Dependency `yaml:"dependencies"`
+	// Include lists the configs (local paths, relative to this config, or URLs) this config is layered on top of
+	Include []string `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	// Vars holds the default values of the variables expanded in the config, see ConfigOptions
+	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty" toml:"vars,omitempty"`
+	Go   *GoConfig         `yaml:"go,omitempty" json:"go,omitempty" toml:"go,omitempty"`
+	// Exclude lists the modules, as paths or path.Match globs (e.g. github.com/onsi/*), that are never upgraded
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
+	// SkipVersions lists, per module, the versions that are never upgraded to (e.g. a broken release)
+	SkipVersions map[string][]string `yaml:"skipVersions,omitempty" json:"skipVersions,omitempty" toml:"skipVersions,omitempty"`
+	Dependencies []Dependency        `yaml:"dependencies" json:"dependencies" toml:"dependencies"`
+}
+
+// ConfigFormat is the file format of a config
+type ConfigFormat string
+
+const (
+	ConfigFormatYAML ConfigFormat = "yaml"
+	ConfigFormatJSON ConfigFormat = "json"
+	ConfigFormatTOML ConfigFormat = "toml"
+)
+
+// ConfigOptions struct to hold how a config is read and its variables expanded
+type ConfigOptions struct {
+	Vars       map[string]string // variables set with --set, overriding the environment and the vars block
+	MatrixPath string            // compatibility matrix providing {{ .Matrix }}
+	Format     ConfigFormat      // format of the config, detected from its extension if empty
+}
+
+// GoConfig struct to hold how the go and toolchain directives of the project are handled
+type GoConfig struct {
+	// Apply updates the go directive explicitly with 'go mod edit' when an upgraded module
+	// requires a newer go version, instead of only reporting that go get will bump it
+	Apply bool `yaml:"apply,omitempty" json:"apply,omitempty" toml:"apply,omitempty"`
+	// Toolchain is the toolchain directive to set together with an applied go directive change (e.g. go1.23.4)
+	Toolchain string `yaml:"toolchain,omitempty" json:"toolchain,omitempty" toml:"toolchain,omitempty"`
+	// MaxVersion is the highest go version the project accepts; upgrades requiring a newer one are refused
+	MaxVersion string `yaml:"maxVersion,omitempty" json:"maxVersion,omitempty" toml:"maxVersion,omitempty"`
 }
 
 // Dependency struct to hold package version or branch information
 type Dependency struct {
-	Package string `yaml:"package"`
-	Version string `yaml:"version,omitempty"`
-	Branch  string `yaml:"branch,omitempty"`
+	Package string `yaml:"package" json:"package" toml:"package"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty" toml:"version,omitempty"`
+	Branch  string `yaml:"branch,omitempty" json:"branch,omitempty" toml:"branch,omitempty"`
+	// Group names a set of dependencies that are upgraded together with a single 'go get',
+	// so that their versions are resolved consistently (e.g. the k8s.io staging modules)
+	Group string `yaml:"group,omitempty" json:"group,omitempty" toml:"group,omitempty"`
+	// SourceBranch records the branch a pinned version was resolved from by 'generate --pin';
+	// it is informational only, the version is what gets upgraded to
+	SourceBranch string `yaml:"sourceBranch,omitempty" json:"sourceBranch,omitempty" toml:"sourceBranch,omitempty"`
+	// AddIfMissing adds the module to the project with 'go get' when the project doesn't require it yet,
+	// instead of skipping it
+	AddIfMissing bool `yaml:"addIfMissing,omitempty" json:"addIfMissing,omitempty" toml:"addIfMissing,omitempty"`
+}
+
+// TrackingMode describes how generate derives the version of a profile dependency
+type TrackingMode string
+
+const (
+	// TrackVersion uses the version required by the matching operator-sdk release
+	TrackVersion TrackingMode = "version"
+	// TrackBranch uses the release-<openshift-version> branch
+	TrackBranch TrackingMode = "branch"
+)
+
+// Profile struct to hold the list of dependencies generate includes in the config
+type Profile struct {
+	Dependencies []ProfileDependency `yaml:"dependencies"`
+}
+
+// ProfileDependency struct to hold a package and how its version is derived
+type ProfileDependency struct {
+	Package string       `yaml:"package"`
+	Track   TrackingMode `yaml:"track"`
+}
+
+// MatchPolicy describes how generate chooses between the operator-sdk releases matching the target Kubernetes version
+type MatchPolicy string
+
+const (
+	// MatchHighest chooses the highest matching release
+	MatchHighest MatchPolicy = "highest"
+	// MatchClosest chooses the highest patch release of the matching minor version closest to the operator-sdk version in use
+	MatchClosest MatchPolicy = "closest"
+	// MatchExactKubernetesPatch chooses the highest matching release using exactly the same Kubernetes patch version,
+	// falling back to the highest matching release if there is none
+	MatchExactKubernetesPatch MatchPolicy = "exact-k8s-patch"
+)
+
+// OperatorSdkCandidate struct to hold an operator-sdk release and the Kubernetes version it uses
+type OperatorSdkCandidate struct {
+	Version           string
+	KubernetesVersion string
+}
+
+// GenerateOptions struct to hold the optional behavior of generate
+type GenerateOptions struct {
+	ProfilePath        string       // profile listing the dependencies to include, the default profile if empty
+	IncludePrereleases bool         // consider operator-sdk prereleases as candidates
+	MatchPolicy        MatchPolicy  // how to choose between the matching operator-sdk releases
+	OperatorSdkVersion string       // operator-sdk release to use instead of searching for a match
+	Offline            bool         // read the versions from the compatibility matrix instead of upstream
+	MatrixPath         string       // compatibility matrix file used in offline mode
+	Distribution       string       // OpenShift distribution of the target version, ocp if empty
+	Pin                bool         // resolve branch-tracked dependencies to pseudo-versions
+	Merge              bool         // merge into the existing config instead of overwriting it
+	Format             ConfigFormat // format of the config, detected from its extension if empty
+}
+
+// Distribution struct to hold where the Kubernetes version of the releases of an OpenShift distribution is read from
+type Distribution struct {
+	Name       string
+	Repository string // GitHub repository whose release-X.Y branches require the k8s.io modules of release X.Y
+	Normalize  bool   // reduce versions of the distribution, e.g. 4.17.0-okd-scos.0, to the X.Y of their release branch
+}
+
+// CompatibilityMatrix struct to hold the dependency versions matching each OpenShift release
+type CompatibilityMatrix struct {
+	Version   int           `yaml:"version"`
+	Refreshed string        `yaml:"refreshed"`
+	Entries   []MatrixEntry `yaml:"entries"`
 }
 
+// MatrixEntry struct to hold the versions matching an OpenShift release
+type MatrixEntry struct {
+	Openshift            string    `yaml:"openshift" json:"openshift"`
+	Kubernetes           string    `yaml:"kubernetes" json:"kubernetes"`
+	OperatorSdk          string    `yaml:"operatorSdk" json:"operatorSdk"`
+	ControllerRuntime    string    `yaml:"controllerRuntime" json:"controllerRuntime"`
+	ControllerTools      string    `yaml:"controllerTools" json:"controllerTools"`
+	OperatorFrameworkAPI string    `yaml:"operatorFrameworkApi" json:"operatorFrameworkApi"`
+	OperatorRegistry     string    `yaml:"operatorRegistry" json:"operatorRegistry"`
+	KubernetesModules    []Package `yaml:"kubernetesModules,omitempty" json:"kubernetesModules,omitempty"`
+}
+
+// MatrixFormat is the output format of the matrix command
+type MatrixFormat string
+
+const (
+	MatrixFormatTable MatrixFormat = "table"
+	MatrixFormatCSV   MatrixFormat = "csv"
+	MatrixFormatJSON  MatrixFormat = "json"
+)
+
 type Package struct {
-	Path    string `json:"Path"`
-	Version string `json:"Version"`
+	Path    string `json:"Path" yaml:"path"`
+	Version string `json:"Version" yaml:"version"`
 }
 
 type Module struct {
-	Require []Package `json:"Require"`
+	Go        string    `json:"Go"`
+	Toolchain string    `json:"Toolchain"`
+	Require   []Package `json:"Require"`
 }
 
 // Commit is a structure representing the commit response from GitHub API
@@ -32,6 +168,89 @@ type Commit struct {
 	} `json:"commit"`
 }
 
+// Lockfile struct to hold the exact versions the dependencies of a config were resolved to
+type Lockfile struct {
+	ConfigHash   string             `yaml:"configHash"`
+	Resolved     string             `yaml:"resolved"`
+	Dependencies []LockedDependency `yaml:"dependencies"`
+}
+
+// LockedDependency struct to hold the version a dependency of the config was resolved to, and where it comes from
+type LockedDependency struct {
+	Package      string `yaml:"package"`
+	Version      string `yaml:"version"`
+	Source       string `yaml:"source"` // "version" for versions taken as is from the config, "branch <branch>" for resolved branches
+	Group        string `yaml:"group,omitempty"`
+	AddIfMissing bool   `yaml:"addIfMissing,omitempty"`
+}
+
+// UpgradeOptions struct to hold the optional behavior of an upgrade run
+type UpgradeOptions struct {
+	GitBranch              string // branch to create in the project before upgrading
+	GitCommit              bool   // commit all upgraded dependencies at the end of the run
+	GitCommitPerDependency bool   // commit each upgraded dependency separately
+	Locked                 bool   // upgrade to the versions of the lockfile instead of resolving the config
+	Lockfile               string // path of the lockfile, defaults to goupgrader.lock next to the config
+	Preflight              bool   // check the modules, versions and branches of the config before upgrading anything
+	FailOnMissing          bool   // fail if a dependency without addIfMissing isn't required by the project
+	Config                 ConfigOptions
+	// PullRequest enables pushing the branch and opening a pull request once upgrades are committed
+	PullRequest *PullRequestOptions
+}
+
+// PullRequestOptions struct to hold where and how the pull request of an upgrade run is opened
+type PullRequestOptions struct {
+	Forge      string // github or gitlab
+	URL        string // base URL of the forge API, defaults to the public service
+	Repository string // defaults to the repository of the git remote
+	Remote     string
+	Base       string // defaults to the branch checked out before the upgrade
+	Labels     []string
+	Reviewers  []string
+}
+
+// UpgradeStatus describes what happened to a dependency during an upgrade run
+type UpgradeStatus string
+
+const (
+	UpgradeStatusUpgraded UpgradeStatus = "upgraded"
+	UpgradeStatusAdded    UpgradeStatus = "added"
+	UpgradeStatusUpToDate UpgradeStatus = "up-to-date"
+	UpgradeStatusNotFound UpgradeStatus = "not found in go.mod"
+	UpgradeStatusExcluded UpgradeStatus = "excluded by config"
+	UpgradeStatusSkipped  UpgradeStatus = "skipped version"
+)
+
+// UpgradeResult struct to hold the outcome of upgrading a single dependency
+type UpgradeResult struct {
+	Package string
+	From    string
+	To      string
+	Status  UpgradeStatus
+}
+
+// VendorStats struct to hold the changes made to vendor/modules.txt by 'go mod vendor'
+type VendorStats struct {
+	Added   []string
+	Removed []string
+	Updated []string
+}
+
+// GoDirectiveChange struct to hold the changes made to the go and toolchain directives of the project
+type GoDirectiveChange struct {
+	FromGo        string
+	ToGo          string
+	FromToolchain string
+	ToToolchain   string
+}
+
+// Report struct to hold the summary of an upgrade run
+type Report struct {
+	Results []UpgradeResult
+	Vendor  *VendorStats       // nil when the project is not vendored or nothing was upgraded
+	Go      *GoDirectiveChange // nil when the go and toolchain directives did not change
+}
+
 // MockCommandExecutor simulates the behavior of the commandExecutor interface
 type MockCommandExecutor struct {
 	Outcome   string // Outcome to return for Output() method
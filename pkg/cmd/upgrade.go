@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/rs/zerolog/log"
 	"github.com/rsoaresd/goupgrader/pkg/cmd/flags"
@@ -11,6 +12,9 @@ import (
 
 func NewUpgrade() *cobra.Command {
 	var config, project string
+	var options UpgradeOptions
+	var openPR bool
+	var pullRequest PullRequestOptions
 
 	command := &cobra.Command{
 		Use:   "upgrade --config=<config-path> --project=<project-path>",
@@ -19,93 +23,478 @@ func NewUpgrade() *cobra.Command {
 Each dependency can define a version or a branch, and the tool will apply the appropriate upgrade.`,
 		Args: cobra.ExactArgs(0),
 		RunE: func(_ *cobra.Command, _ []string) error {
-			return Upgrade(config, project)
+			if openPR {
+				options.PullRequest = &pullRequest
+			}
+			_, err := Upgrade(config, project, options)
+			return err
 		},
 	}
 
-	command.Flags().StringVarP(&config, "config", "c", "", "path to YAML config")
+	command.Flags().StringVarP(&config, "config", "c", "", "path or URL of the config, or - to read it from stdin")
 	flags.MustMarkRequired(command, "config")
 	command.Flags().StringVarP(&project, "project", "p", "", "path to the target Go project")
 	flags.MustMarkRequired(command, "project")
+	addConfigFlags(command, &options.Config)
+	command.Flags().BoolVar(&options.Preflight, "preflight", true, "check that the modules, versions and branches of the config exist on proxy.golang.org and in git before upgrading anything (skipped with --locked)")
+	command.Flags().BoolVar(&options.FailOnMissing, "fail-on-missing", false, "fail before upgrading anything if the project doesn't require a dependency of the config, unless it has addIfMissing")
+	command.Flags().BoolVar(&options.Locked, "locked", false, "upgrade to the versions of the lockfile next to the config, failing if the config changed since it was written")
+	command.Flags().StringVar(&options.Lockfile, "lockfile", "", "path of the lockfile (defaults to goupgrader.lock next to the config, none for a config read from stdin or a URL)")
+	command.Flags().StringVar(&options.GitBranch, "git-branch", "", "create this git branch in the project before upgrading")
+	command.Flags().BoolVar(&options.GitCommit, "git-commit", false, "commit the upgraded dependencies in the project")
+	command.Flags().BoolVar(&options.GitCommitPerDependency, "git-commit-per-dependency", false, "commit each upgraded dependency separately (implies --git-commit)")
+	command.Flags().BoolVar(&openPR, "open-pr", false, "push the git branch and open a pull request with the upgrade report (requires --git-branch, implies --git-commit)")
+	command.Flags().StringVar(&pullRequest.Forge, "forge", "github", "forge hosting the project: github (token in GITHUB_TOKEN) or gitlab (token in GITLAB_TOKEN)")
+	command.Flags().StringVar(&pullRequest.URL, "forge-url", "", "base URL of the forge API (defaults to the public github.com or gitlab.com API)")
+	command.Flags().StringVar(&pullRequest.Repository, "forge-repo", "", "repository to open the pull request in, e.g. owner/name (defaults to the repository of --git-remote)")
+	command.Flags().StringVar(&pullRequest.Remote, "git-remote", "origin", "git remote to push the branch to")
+	command.Flags().StringVar(&pullRequest.Base, "pr-base", "", "branch the pull request targets (defaults to the branch checked out before upgrading)")
+	command.Flags().StringSliceVar(&pullRequest.Labels, "pr-label", nil, "label to add to the pull request (can be repeated)")
+	command.Flags().StringSliceVar(&pullRequest.Reviewers, "pr-reviewer", nil, "username to request a review from (can be repeated)")
 
 	return command
 }
 
 // Upgrade performs the upgrade of project dependencies based on the provided configuration.
-// It takes in two parameters:
+// It takes in three parameters:
 // - configPath: The file path to the YAML configuration that contains dependency details.
 // - projectPath: The file path to the Go project that needs the upgrades.
+// - options: The optional behavior of the upgrade run, such as the git integration.
 //
 // The function does the following:
-// 1. It parses the configuration file using `parseConfig`, which returns a list of dependencies to upgrade.
-// 2. It iterates over each dependency in the configuration:
-//   - If the dependency has a specified version, it calls `upgradePackage` to upgrade that package to the given version.
-//   - If the dependency specifies a branch, it fetches the corresponding version (commit hash) for that branch using `getVersionWithCommitHashForBranch`, and then upgrades the package to that version.
+// 1. It parses the configuration file using `parseConfig`, which returns a list of dependencies to upgrade,
+// with the variables of the config expanded using the `Config` options. With the `Preflight` option, unless the versions
+// are locked, it then checks with `preflight` that every module, version and branch of the config exists, reporting all
+// problems before anything is changed.
+// 2. With the `Locked` option, it reads the versions to upgrade to from the lockfile next to the config (or the
+// `Lockfile` option), which must have been written for the same config.
+// With the `FailOnMissing` option, it fails if the project doesn't require one of the dependencies of the config,
+// unless the dependency is to be added if missing (`addIfMissing`), before anything is changed.
+// 3. If a git branch or commit is requested, it makes sure the working tree of the project is clean and creates the branch.
+// 4. Without the `Locked` option, it resolves each dependency to an exact version and writes them to the lockfile,
+// if the config has one (see `lockfilePath`):
+// a specified version is used as is, and a branch is resolved to the version (commit hash) of its latest commit
+// using `getVersionWithCommitHashForBranch`.
+// 5. It iterates over each dependency, or over each group of dependencies sharing the same `group`,
+// and calls `upgradePackages` to upgrade the packages to their resolved versions.
+// The dependencies matching the `exclude` patterns of the config, and the ones resolved to one of their `skipVersions`
+// along with the rest of their group, are left untouched and reported as such.
+// The dependencies of a group are upgraded together with a single `go get`, which must not move any module of the
+// project to one of its `skipVersions`.
 //
-// 3. If any errors are encountered during the upgrade process (either parsing the config, upgrading a package, or fetching a branch version), it returns the error.
-// 4. Once all dependencies have been processed successfully, it returns `nil`, indicating the upgrade process is complete.
-func Upgrade(configPath, projectPath string) error {
-	config, err := parseConfig(configPath)
+// 6. If the config has a `go` section, the go version required by each upgraded module is checked against the
+// project's go directive and the configured maximum go version before upgrading (see `prepareGoDirective`), and the
+// go directive is checked again against the maximum go version after upgrading (see `checkGoDirective`).
+// Any change to the go or toolchain directives is recorded in the report.
+// 7. If the project vendors its dependencies and at least one package was upgraded, it runs `go mod vendor`
+// followed by `go mod verify` so that the vendor directory stays consistent with go.mod.
+// 8. If a git commit is requested, it commits the changes either once for the whole run or once per upgraded dependency.
+// 9. If a pull request is requested, it pushes the branch and opens (or updates) a pull request with the report as body.
+// 10. If any errors are encountered during the upgrade process (either parsing the config, upgrading a package, or fetching a branch version), it returns the error.
+// 11. Once all dependencies have been processed successfully, it returns a report of what was done.
+func Upgrade(configPath, projectPath string, options UpgradeOptions) (*Report, error) {
+	config, err := parseConfig(configPath, options.Config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, dependency := range config.Dependencies {
-		if dependency.Version != "" {
-			err := upgradePackage(projectPath, dependency.Package, dependency.Version)
-			if err != nil {
-				return err
+	if err := validatePullRequestOptions(options); err != nil {
+		return nil, err
+	}
+
+	// the excluded dependencies are left out of every step of the upgrade
+	dependencies, excluded := liveDependencies(config)
+	for _, result := range excluded {
+		log.Info().Msgf("skipping %s: excluded by config", result.Package)
+	}
+
+	// locked versions were resolved when the lockfile was written, whatever happened to the branches since
+	if options.Preflight && !options.Locked {
+		if err := preflight(dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.FailOnMissing {
+		if err := checkMissingDependencies(projectPath, dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	var lock *Lockfile
+	if options.Locked {
+		if lock, err = readLockfile(configPath, options.Lockfile, options.Config); err != nil {
+			return nil, err
+		}
+		log.Info().Msgf("using the versions resolved at %s in %s", lock.Resolved, lockfilePath(configPath, options.Lockfile))
+	}
+
+	commit := options.GitCommit || options.GitCommitPerDependency || options.PullRequest != nil
+	if commit || options.GitBranch != "" {
+		if err := ensureCleanWorkingTree(projectPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// the pull request targets the branch the upgrade started from, unless told otherwise
+	var base string
+	if options.PullRequest != nil {
+		if base = options.PullRequest.Base; base == "" {
+			if base, err = currentBranch(projectPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if options.GitBranch != "" {
+		var remote string
+		if options.PullRequest != nil {
+			remote = options.PullRequest.Remote
+		}
+		if err := createBranch(projectPath, options.GitBranch, remote); err != nil {
+			return nil, err
+		}
+	}
+
+	vendored, err := isVendored(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	goBefore, toolchainBefore, err := getGoDirective(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var vendoredBefore map[string]string
+	if vendored {
+		if vendoredBefore, err = readVendoredModules(projectPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// resolve every dependency to an exact version, written to the lockfile on the new branch if any,
+	// so that the lockfile is committed with the upgrade when it is in the project
+	if !options.Locked {
+		if lock, err = resolveDependencies(config, dependencies); err != nil {
+			return nil, err
+		}
+		if path := lockfilePath(configPath, options.Lockfile); path != "" {
+			if err := writeLockfile(path, lock); err != nil {
+				return nil, err
 			}
 		}
+	}
+
+	report := &Report{Results: excluded}
+	for _, dependencies := range groupDependencies(lockedDependencies(lock)) {
+		dependencies, skipped := skipVersions(config, dependencies)
+		report.Results = append(report.Results, skipped...)
+		if len(dependencies) == 0 {
+			continue
+		}
 
-		if dependency.Branch != "" {
-			targetVersion, err := getVersionWithCommitHashForBranch(dependency.Package, dependency.Branch)
-			if err != nil {
-				return err
+		results, err := upgradePackages(projectPath, config, dependencies)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, results...)
+
+		// each commit must leave the project consistent, including its vendor directory
+		if options.GitCommitPerDependency && hasUpgrades(results) {
+			if vendored {
+				if err := revendor(projectPath); err != nil {
+					return nil, err
+				}
 			}
+			if err := commitChanges(projectPath, commitMessage(results)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	goAfter, toolchainAfter, err := getGoDirective(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if goBefore != goAfter || toolchainBefore != toolchainAfter {
+		report.Go = &GoDirectiveChange{
+			FromGo:        goBefore,
+			ToGo:          goAfter,
+			FromToolchain: toolchainBefore,
+			ToToolchain:   toolchainAfter,
+		}
+	}
 
-			err = upgradePackage(projectPath, dependency.Package, targetVersion)
-			if err != nil {
-				return err
+	if vendored && report.hasUpgrades() {
+		if !options.GitCommitPerDependency {
+			if err := revendor(projectPath); err != nil {
+				return nil, err
 			}
 		}
+
+		vendoredAfter, err := readVendoredModules(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		report.Vendor = diffVendoredModules(vendoredBefore, vendoredAfter)
+	}
+
+	if commit && !options.GitCommitPerDependency && report.hasUpgrades() {
+		if err := commitChanges(projectPath, commitMessage(report.Results)); err != nil {
+			return nil, err
+		}
+	}
+
+	logReport(report)
+
+	if options.PullRequest != nil {
+		if !report.hasUpgrades() {
+			log.Info().Msg("nothing was upgraded: skipping pull request")
+			return report, nil
+		}
+		if err := openPullRequest(projectPath, options.GitBranch, base, report, *options.PullRequest); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	return report, nil
 }
 
-func upgradePackage(projectPath, packageName, targetVersion string) error {
-	currentVersion, err := getPackageVersion(projectPath, packageName)
-	if err != nil {
-		if errors.Is(err, ErrPackageNotFound) {
-			log.Info().Msgf("skipping %s: not found in go.mod", packageName)
-			return nil
+// checkMissingDependencies returns an error listing the dependencies the project doesn't require, except the ones
+// to add if missing.
+func checkMissingDependencies(projectPath string, dependencies []Dependency) error {
+	var errs []error
+	for _, dependency := range dependencies {
+		if dependency.AddIfMissing {
+			continue
+		}
+		if _, err := getPackageVersion(projectPath, dependency.Package); errors.Is(err, ErrPackageNotFound) {
+			errs = append(errs, fmt.Errorf("dependency %s: not found in go.mod", dependency.Package))
+		} else if err != nil {
+			return err
 		}
-		return err
 	}
+	return errors.Join(errs...)
+}
 
-	log.Info().Msgf("upgrading %s from %s to %s...", packageName, currentVersion, targetVersion)
+// liveDependencies splits the dependencies of the config into the ones goupgrader works on, from the preflight to
+// the lockfile and the upgrade, and the results of the ones matching its exclude patterns, which are left untouched.
+func liveDependencies(config *Config) ([]Dependency, []UpgradeResult) {
+	var live []Dependency
+	var excluded []UpgradeResult
+	for _, dependency := range config.Dependencies {
+		if config.isExcluded(dependency.Package) {
+			excluded = append(excluded, UpgradeResult{Package: dependency.Package, Status: UpgradeStatusExcluded})
+			continue
+		}
+		live = append(live, dependency)
+	}
+	return live, excluded
+}
+
+// skipVersions returns the dependencies upgraded together, unless the resolved version of one of them is one of the
+// skipped versions of the config, in which case it returns the results of all of them, which are left untouched:
+// the dependencies of a group are only consistent at the same version.
+func skipVersions(config *Config, dependencies []Dependency) ([]Dependency, []UpgradeResult) {
+	i := slices.IndexFunc(dependencies, func(dependency Dependency) bool {
+		return config.isSkippedVersion(dependency.Package, dependency.Version)
+	})
+	if i < 0 {
+		return dependencies, nil
+	}
+
+	log.Info().Msgf("skipping %s: version %s of %s is skipped by config",
+		describeDependencies(dependencies), dependencies[i].Version, dependencies[i].Package)
+	var skipped []UpgradeResult
+	for _, dependency := range dependencies {
+		skipped = append(skipped, UpgradeResult{Package: dependency.Package, To: dependency.Version, Status: UpgradeStatusSkipped})
+	}
+	return nil, skipped
+}
+
+// groupDependencies splits the dependencies into the sets upgraded together: the dependencies of a group
+// are upgraded together at the position of the first of them, every other dependency on its own.
+func groupDependencies(dependencies []Dependency) [][]Dependency {
+	var sets [][]Dependency
+	groupIndex := map[string]int{}
+
+	for _, dependency := range dependencies {
+		if dependency.Group == "" {
+			sets = append(sets, []Dependency{dependency})
+			continue
+		}
+
+		if i, found := groupIndex[dependency.Group]; found {
+			sets[i] = append(sets[i], dependency)
+			continue
+		}
+
+		groupIndex[dependency.Group] = len(sets)
+		sets = append(sets, []Dependency{dependency})
+	}
 
-	// if the current version is lower than the target version, upgrade
-	if currentVersion < targetVersion {
-		// upgrade package
-		cmd := goCommandFunc(true, projectPath, "get", fmt.Sprintf("%s@%s", packageName, targetVersion))
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("error upgrading dependency %s: %w", packageName, err)
+	return sets
+}
+
+// describeDependencies describes the dependencies upgraded together in messages: the dependency itself,
+// or the group they belong to.
+func describeDependencies(dependencies []Dependency) string {
+	if len(dependencies) == 1 {
+		return fmt.Sprintf("dependency %s", dependencies[0].Package)
+	}
+	return fmt.Sprintf("dependency group %s", dependencies[0].Group)
+}
+
+// upgradePackages upgrades the packages of the given dependencies, whose version is already resolved,
+// with a single 'go get' so that their versions are resolved consistently, followed by 'go mod tidy'.
+// The go directive and the modules it moved are then checked against the go section and the skipped versions
+// of the config.
+func upgradePackages(projectPath string, config *Config, dependencies []Dependency) ([]UpgradeResult, error) {
+	goConfig := config.Go
+	var results []UpgradeResult
+	var getArgs []string
+
+	for _, dependency := range dependencies {
+		packageName, targetVersion := dependency.Package, dependency.Version
+		result := UpgradeResult{Package: packageName, To: targetVersion}
+
+		currentVersion, err := getPackageVersion(projectPath, packageName)
+		if err != nil {
+			if !errors.Is(err, ErrPackageNotFound) {
+				return nil, err
+			}
+			if !dependency.AddIfMissing {
+				log.Info().Msgf("skipping %s: not found in go.mod", packageName)
+				result.Status = UpgradeStatusNotFound
+				results = append(results, result)
+				continue
+			}
+			log.Info().Msgf("adding %s at %s: not found in go.mod", packageName, targetVersion)
+		} else {
+			result.From = currentVersion
+			log.Info().Msgf("upgrading %s from %s to %s...", packageName, currentVersion, targetVersion)
 		}
 
-		// run go mod tidy
-		cmd = goCommandFunc(true, projectPath, "mod", "tidy")
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("error running go mod tidy: %w", err)
+		// if the current version is lower than the target version, or there is none, upgrade
+		if currentVersion < targetVersion {
+			// make sure the go directive can accommodate the new version
+			if goConfig != nil {
+				if err := prepareGoDirective(projectPath, goConfig, packageName, targetVersion); err != nil {
+					return nil, err
+				}
+			}
+
+			getArgs = append(getArgs, fmt.Sprintf("%s@%s", packageName, targetVersion))
+			result.Status = UpgradeStatusUpgraded
+			if currentVersion == "" {
+				result.Status = UpgradeStatusAdded
+			}
+		} else {
+			log.Info().Msgf("no upgrade needed for %s: current version %s >= requested version %s",
+				packageName, currentVersion, targetVersion)
+			result.Status = UpgradeStatusUpToDate
 		}
 
-		log.Info().Msgf("upgrade %s from %s to %s finished successfully", packageName, currentVersion, targetVersion)
+		results = append(results, result)
+	}
+
+	if len(getArgs) == 0 {
+		return results, nil
+	}
 
-	} else {
-		log.Info().Msgf("no upgrade needed for %s: current version %s >= requested version %s",
-			packageName, currentVersion, targetVersion)
+	var versionsBefore map[string]string
+	if len(config.SkipVersions) > 0 || len(config.Exclude) > 0 {
+		var err error
+		if versionsBefore, err = requiredVersions(projectPath); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	restoreModFiles, err := saveModFiles(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// upgrade packages
+	cmd := goCommandFunc(true, projectPath, append([]string{"get"}, getArgs...)...)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error upgrading %s: %w", describeDependencies(dependencies), err)
+	}
+
+	// run go mod tidy
+	cmd = goCommandFunc(true, projectPath, "mod", "tidy")
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running go mod tidy: %w", err)
+	}
+
+	// the modules pulled in by the upgrade may have raised the go directive too, moved an excluded module
+	// or be at a skipped version, in which case the upgrade is undone
+	var refused error
+	if goConfig != nil && goConfig.MaxVersion != "" {
+		refused = checkGoDirective(projectPath, goConfig, describeDependencies(dependencies))
+	}
+	if refused == nil && versionsBefore != nil {
+		refused = checkMovedModules(projectPath, config, versionsBefore, describeDependencies(dependencies))
+	}
+	if refused != nil {
+		if err := restoreModFiles(); err != nil {
+			return nil, errors.Join(refused, err)
+		}
+		return nil, refused
+	}
+
+	for _, result := range results {
+		switch result.Status {
+		case UpgradeStatusUpgraded:
+			log.Info().Msgf("upgrade %s from %s to %s finished successfully", result.Package, result.From, result.To)
+		case UpgradeStatusAdded:
+			log.Info().Msgf("adding %s at %s finished successfully", result.Package, result.To)
+		}
+	}
+
+	return results, nil
+}
+
+// requiredVersions returns the version of each module required by the project.
+func requiredVersions(projectPath string) (map[string]string, error) {
+	module, err := readModule(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[string]string{}
+	for _, pkg := range module.Require {
+		versions[pkg.Path] = pkg.Version
+	}
+	return versions, nil
+}
+
+// checkMovedModules returns an error if upgrading the given dependencies moved a module required by the project that
+// is excluded by the config, or to one of the skipped versions of the config, e.g. because an upgraded module requires it.
+func checkMovedModules(projectPath string, config *Config, versionsBefore map[string]string, upgraded string) error {
+	versionsAfter, err := requiredVersions(projectPath)
+	if err != nil {
+		return err
+	}
+
+	var modulePaths []string
+	for modulePath := range versionsAfter {
+		modulePaths = append(modulePaths, modulePath)
+	}
+	slices.Sort(modulePaths)
+
+	var errs []error
+	for _, modulePath := range modulePaths {
+		version, versionBefore := versionsAfter[modulePath], versionsBefore[modulePath]
+		switch {
+		case version == versionBefore:
+		case versionBefore != "" && config.isExcluded(modulePath):
+			errs = append(errs, fmt.Errorf("upgrading %s moved %s from %s to %s, but it is excluded by config",
+				upgraded, modulePath, versionBefore, version))
+		case config.isSkippedVersion(modulePath, version):
+			errs = append(errs, fmt.Errorf("upgrading %s moved %s to %s, which is skipped by config", upgraded, modulePath, version))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -3,8 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -124,6 +127,7 @@ func TestUpgrade(t *testing.T) {
 			args := []string{
 				fmt.Sprintf("--config=%s", tmpFile.Name()),
 				fmt.Sprintf("--project=%s", tt.targetDir),
+				"--preflight=false",
 			}
 			cmd.SetArgs(args)
 
@@ -138,3 +142,305 @@ func TestUpgrade(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupDependencies(t *testing.T) {
+	sets := groupDependencies([]Dependency{
+		{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"},
+		{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+		{Package: "github.com/openshift/api", Branch: "release-4.18"},
+		{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+	})
+
+	assert.Equal(t, [][]Dependency{
+		{{Package: "sigs.k8s.io/controller-runtime", Version: "v0.19.3"}},
+		{
+			{Package: "k8s.io/api", Version: "v0.31.1", Group: "kubernetes"},
+			{Package: "k8s.io/client-go", Version: "v0.31.1", Group: "kubernetes"},
+		},
+		{{Package: "github.com/openshift/api", Branch: "release-4.18"}},
+	}, sets)
+}
+
+func TestUpgradeDependencyGroup(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	config := `dependencies:
+  - package: "k8s.io/api"
+    version: "v0.31.1"
+    group: kubernetes
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "k8s.io/client-go"
+    version: "v0.31.1"
+    group: kubernetes
+  - package: "k8s.io/apimachinery"
+    version: "v0.31.1"
+    group: kubernetes`
+
+	tests := []struct {
+		name             string
+		getErr           error
+		expectedCommands []string
+		expectedError    string
+	}{
+		{
+			name: "group upgraded with a single go get",
+			expectedCommands: []string{
+				"get k8s.io/api@v0.31.1 k8s.io/client-go@v0.31.1",
+				"mod tidy",
+				"get sigs.k8s.io/controller-runtime@v0.19.3",
+				"mod tidy",
+			},
+		},
+		{
+			name:          "go get of the group fails",
+			getErr:        fmt.Errorf("exit status 1"),
+			expectedError: "error upgrading dependency group kubernetes: exit status 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+
+			var commands []string
+			goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+				if arg[0] == "get" || arg[0] == "mod" && arg[1] == "tidy" {
+					commands = append(commands, strings.Join(arg, " "))
+				}
+				return &MockCommandExecutor{
+					Outcome: `{"Require":[{"Path":"k8s.io/api","Version":"v0.30.1"},{"Path":"k8s.io/apimachinery","Version":"v0.31.1"},` +
+						`{"Path":"k8s.io/client-go","Version":"v0.30.1"},{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`,
+					RunErr: tt.getErr,
+				}
+			}
+
+			report, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{})
+
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommands, commands)
+			assert.Equal(t, []UpgradeResult{
+				{Package: "k8s.io/api", From: "v0.30.1", To: "v0.31.1", Status: UpgradeStatusUpgraded},
+				{Package: "k8s.io/client-go", From: "v0.30.1", To: "v0.31.1", Status: UpgradeStatusUpgraded},
+				{Package: "k8s.io/apimachinery", From: "v0.31.1", To: "v0.31.1", Status: UpgradeStatusUpToDate},
+				{Package: "sigs.k8s.io/controller-runtime", From: "v0.18.4", To: "v0.19.3", Status: UpgradeStatusUpgraded},
+			}, report.Results)
+		})
+	}
+}
+
+func TestUpgradeExclusions(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+	resolutions := fakeBranchVersions(t, "v0.0.0-20250301100000-0123456789ab")
+
+	var commands []string
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		if arg[0] == "get" {
+			commands = append(commands, strings.Join(arg, " "))
+		}
+		return &MockCommandExecutor{
+			Outcome: `{"Require":[{"Path":"k8s.io/api","Version":"v0.30.1"},{"Path":"k8s.io/client-go","Version":"v0.30.1"},` +
+				`{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"},{"Path":"github.com/onsi/gomega","Version":"v1.33.0"}]}`,
+		}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`exclude:
+  - github.com/onsi/*
+  - github.com/openshift/api
+skipVersions:
+  k8s.io/client-go: [v0.31.1]
+  sigs.k8s.io/controller-runtime: [v0.20.0]
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.20.0"
+  - package: "github.com/onsi/gomega"
+    version: "v1.34.0"
+  - package: "github.com/openshift/api"
+    branch: "release-4.18"
+  - package: "k8s.io/api"
+    version: "v0.31.1"
+    group: kubernetes
+  - package: "k8s.io/client-go"
+    version: "v0.31.1"
+    group: kubernetes
+`), 0600))
+
+	report, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{})
+
+	require.NoError(t, err)
+	// the skipped version of k8s.io/client-go holds back the whole group
+	assert.Empty(t, commands)
+	assert.Zero(t, *resolutions)
+	assert.Equal(t, []UpgradeResult{
+		{Package: "github.com/onsi/gomega", Status: UpgradeStatusExcluded},
+		{Package: "github.com/openshift/api", Status: UpgradeStatusExcluded},
+		{Package: "sigs.k8s.io/controller-runtime", To: "v0.20.0", Status: UpgradeStatusSkipped},
+		{Package: "k8s.io/api", To: "v0.31.1", Status: UpgradeStatusSkipped},
+		{Package: "k8s.io/client-go", To: "v0.31.1", Status: UpgradeStatusSkipped},
+	}, report.Results)
+}
+
+func TestUpgradeSkippedVersionPulledIn(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	// upgrading controller-runtime pulls in k8s.io/client-go v0.31.1
+	upgraded := false
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		if arg[0] == "get" {
+			upgraded = true
+		}
+		if upgraded {
+			return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"k8s.io/client-go","Version":"v0.31.1"},` +
+				`{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.19.3"}]}`}
+		}
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"k8s.io/client-go","Version":"v0.30.1"},` +
+			`{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`skipVersions:
+  k8s.io/client-go: [v0.31.1]
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+`), 0600))
+
+	_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{})
+
+	require.EqualError(t, err, "upgrading dependency sigs.k8s.io/controller-runtime moved k8s.io/client-go to v0.31.1, which is skipped by config")
+}
+
+func TestUpgradeExcludedModulePulledIn(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	projectPath := t.TempDir()
+	goMod := "module example.com/project\n\ngo 1.22.0\n\nrequire (\n\tgithub.com/onsi/gomega v1.34.1\n\tsigs.k8s.io/controller-runtime v0.18.4\n)\n"
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "go.mod"), []byte(goMod), 0644))
+
+	// upgrading controller-runtime pulls in a newer github.com/onsi/gomega
+	upgraded := false
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		if arg[0] == "get" {
+			upgraded = true
+			require.NoError(t, os.WriteFile(filepath.Join(projectPath, "go.mod"), []byte("module example.com/project\n"), 0644))
+		}
+		if upgraded {
+			return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"github.com/onsi/gomega","Version":"v1.35.1"},` +
+				`{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.19.3"}]}`}
+		}
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"github.com/onsi/gomega","Version":"v1.34.1"},` +
+			`{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`exclude:
+  - github.com/onsi/*
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+`), 0600))
+
+	_, err := Upgrade(configPath, projectPath, UpgradeOptions{})
+
+	require.EqualError(t, err, "upgrading dependency sigs.k8s.io/controller-runtime moved github.com/onsi/gomega from v1.34.1 "+
+		"to v1.35.1, but it is excluded by config")
+	content, err := os.ReadFile(filepath.Join(projectPath, "go.mod"))
+	require.NoError(t, err)
+	assert.Equal(t, goMod, string(content))
+}
+
+func TestUpgradeMissingDependencies(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	var commands []string
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		if arg[0] == "get" {
+			commands = append(commands, strings.Join(arg, " "))
+		}
+		return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+  - package: "sigs.k8s.io/controller-tools"
+    version: "v0.16.5"
+    addIfMissing: true
+  - package: "github.com/onsi/ginkgo/v2"
+    version: "v2.20.0"
+`), 0600))
+
+	t.Run("added if missing", func(t *testing.T) {
+		commands = nil
+
+		report, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"get sigs.k8s.io/controller-runtime@v0.19.3", "get sigs.k8s.io/controller-tools@v0.16.5"}, commands)
+		assert.Equal(t, []UpgradeResult{
+			{Package: "sigs.k8s.io/controller-runtime", From: "v0.18.4", To: "v0.19.3", Status: UpgradeStatusUpgraded},
+			{Package: "sigs.k8s.io/controller-tools", To: "v0.16.5", Status: UpgradeStatusAdded},
+			{Package: "github.com/onsi/ginkgo/v2", To: "v2.20.0", Status: UpgradeStatusNotFound},
+		}, report.Results)
+	})
+
+	t.Run("fail on missing", func(t *testing.T) {
+		commands = nil
+
+		_, err := Upgrade(configPath, "/path/to/project", UpgradeOptions{FailOnMissing: true})
+
+		require.EqualError(t, err, "dependency github.com/onsi/ginkgo/v2: not found in go.mod")
+		assert.Empty(t, commands)
+	})
+}
+
+func TestUpgradeGoDirectiveAboveMaximum(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	projectPath := t.TempDir()
+	goMod := "module example.com/project\n\ngo 1.22.0\n\nrequire sigs.k8s.io/controller-runtime v0.18.4\n"
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "go.mod"), []byte(goMod), 0644))
+
+	// controller-runtime itself fits the maximum go version, but one of the modules it pulls in doesn't
+	goVersion := "1.22.0"
+	goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+		switch arg[0] {
+		case "list":
+			return &MockCommandExecutor{Outcome: `{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.19.3","GoVersion":"1.22.0"}`}
+		case "get":
+			goVersion = "1.23.0"
+			require.NoError(t, os.WriteFile(filepath.Join(projectPath, "go.mod"),
+				[]byte("module example.com/project\n\ngo 1.23.0\n\nrequire sigs.k8s.io/controller-runtime v0.19.3\n"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(projectPath, "go.sum"), []byte("sigs.k8s.io/controller-runtime v0.19.3 h1:\n"), 0644))
+		}
+		return &MockCommandExecutor{
+			Outcome: fmt.Sprintf(`{"Go":%q,"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`, goVersion),
+		}
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`go:
+  maxVersion: "1.22.7"
+dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"
+`), 0600))
+
+	_, err := Upgrade(configPath, projectPath, UpgradeOptions{})
+
+	require.EqualError(t, err, "upgrading dependency sigs.k8s.io/controller-runtime raised the go directive to 1.23.0, "+
+		"which exceeds the maximum go version 1.22.7")
+	// the refused upgrade leaves go.mod and go.sum as they were
+	content, err := os.ReadFile(filepath.Join(projectPath, "go.mod"))
+	require.NoError(t, err)
+	assert.Equal(t, goMod, string(content))
+	assert.NoFileExists(t, filepath.Join(projectPath, "go.sum"))
+}
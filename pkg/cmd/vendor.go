@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// isVendored reports whether the project at projectPath commits its dependencies
+// in a vendor directory, either because vendor/modules.txt exists or because
+// GOFLAGS forces the vendor build mode.
+func isVendored(projectPath string) (bool, error) {
+	_, err := os.Stat(filepath.Join(projectPath, "vendor", "modules.txt"))
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to check vendor directory: %w", err)
+	}
+
+	// GOFLAGS may come from the environment or from 'go env -w', so ask go itself
+	output, err := goCommandFunc(false, projectPath, "env", "GOFLAGS").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to run 'go env GOFLAGS': %w", err)
+	}
+
+	for _, flag := range strings.Fields(string(output)) {
+		if flag == "-mod=vendor" || flag == "--mod=vendor" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// readVendoredModules parses vendor/modules.txt and returns the vendored module versions
+// keyed by module path. A missing modules.txt results in an empty map.
+func readVendoredModules(projectPath string) (map[string]string, error) {
+	modules := map[string]string{}
+
+	file, err := os.Open(filepath.Join(projectPath, "vendor", "modules.txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return modules, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// module lines look like "# path version" or "# path version => replacement"
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		if len(fields) < 2 || fields[1] == "=>" {
+			continue
+		}
+		modules[fields[0]] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vendor/modules.txt: %w", err)
+	}
+
+	return modules, nil
+}
+
+// diffVendoredModules compares two snapshots of vendor/modules.txt.
+func diffVendoredModules(before, after map[string]string) *VendorStats {
+	stats := &VendorStats{}
+
+	for path, version := range after {
+		previous, found := before[path]
+		switch {
+		case !found:
+			stats.Added = append(stats.Added, path)
+		case previous != version:
+			stats.Updated = append(stats.Updated, path)
+		}
+	}
+
+	for path := range before {
+		if _, found := after[path]; !found {
+			stats.Removed = append(stats.Removed, path)
+		}
+	}
+
+	sort.Strings(stats.Added)
+	sort.Strings(stats.Updated)
+	sort.Strings(stats.Removed)
+
+	return stats
+}
+
+// revendor runs 'go mod vendor' in a vendored project so that the vendor directory matches
+// the upgraded go.mod, and then checks the module cache with 'go mod verify'.
+func revendor(projectPath string) error {
+	log.Info().Msg("running go mod vendor...")
+	if err := goCommandFunc(true, projectPath, "mod", "vendor").Run(); err != nil {
+		return fmt.Errorf("error running go mod vendor: %w", err)
+	}
+
+	log.Info().Msg("running go mod verify...")
+	if err := goCommandFunc(true, projectPath, "mod", "verify").Run(); err != nil {
+		return fmt.Errorf("error running go mod verify: %w", err)
+	}
+
+	return nil
+}
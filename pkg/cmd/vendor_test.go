@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModulesTxt(t *testing.T, projectPath, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(projectPath, "vendor"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(projectPath, "vendor", "modules.txt"), []byte(content), 0600))
+}
+
+func TestIsVendored(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	t.Run("vendor/modules.txt exists", func(t *testing.T) {
+		projectPath := t.TempDir()
+		writeModulesTxt(t, projectPath, "")
+		goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+			return &MockCommandExecutor{}
+		}
+
+		vendored, err := isVendored(projectPath)
+		require.NoError(t, err)
+		assert.True(t, vendored)
+	})
+
+	t.Run("GOFLAGS forces vendor mode", func(t *testing.T) {
+		goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+			return &MockCommandExecutor{Outcome: "-trimpath -mod=vendor\n"}
+		}
+
+		vendored, err := isVendored(t.TempDir())
+		require.NoError(t, err)
+		assert.True(t, vendored)
+	})
+
+	t.Run("not vendored", func(t *testing.T) {
+		goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+			return &MockCommandExecutor{Outcome: "-mod=mod\n"}
+		}
+
+		vendored, err := isVendored(t.TempDir())
+		require.NoError(t, err)
+		assert.False(t, vendored)
+	})
+
+	t.Run("error running go env", func(t *testing.T) {
+		goCommandFunc = func(_ bool, _ string, _ ...string) commandExecutor {
+			return &MockCommandExecutor{OutputErr: fmt.Errorf("go not found")}
+		}
+
+		_, err := isVendored(t.TempDir())
+		require.EqualError(t, err, "failed to run 'go env GOFLAGS': go not found")
+	})
+}
+
+func TestReadVendoredModules(t *testing.T) {
+	projectPath := t.TempDir()
+	writeModulesTxt(t, projectPath, `# github.com/go-logr/logr v1.4.2
+## explicit; go 1.18
+github.com/go-logr/logr
+# k8s.io/api v0.31.1 => ../api
+## explicit; go 1.22.0
+k8s.io/api/core/v1
+# k8s.io/api => ../api
+`)
+
+	modules, err := readVendoredModules(projectPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"github.com/go-logr/logr": "v1.4.2",
+		"k8s.io/api":              "v0.31.1",
+	}, modules)
+
+	t.Run("missing modules.txt", func(t *testing.T) {
+		modules, err := readVendoredModules(t.TempDir())
+		require.NoError(t, err)
+		assert.Empty(t, modules)
+	})
+}
+
+func TestDiffVendoredModules(t *testing.T) {
+	before := map[string]string{
+		"sigs.k8s.io/controller-runtime": "v0.18.4",
+		"k8s.io/api":                     "v0.30.1",
+		"github.com/evanphx/json-patch":  "v4.12.0+incompatible",
+	}
+	after := map[string]string{
+		"sigs.k8s.io/controller-runtime": "v0.19.3",
+		"k8s.io/api":                     "v0.30.1",
+		"github.com/fxamacker/cbor/v2":   "v2.7.0",
+	}
+
+	stats := diffVendoredModules(before, after)
+
+	assert.Equal(t, []string{"github.com/fxamacker/cbor/v2"}, stats.Added)
+	assert.Equal(t, []string{"sigs.k8s.io/controller-runtime"}, stats.Updated)
+	assert.Equal(t, []string{"github.com/evanphx/json-patch"}, stats.Removed)
+}
+
+func TestUpgradeVendoredProject(t *testing.T) {
+	origGoCommandFunc := goCommandFunc
+	defer func() { goCommandFunc = origGoCommandFunc }()
+
+	tests := []struct {
+		name             string
+		verifyErr        error
+		expectedCommands []string
+		expectedError    string
+	}{
+		{
+			name: "runs go mod vendor and go mod verify after upgrading",
+			expectedCommands: []string{
+				"mod edit -json",
+				"mod edit -json",
+				"get sigs.k8s.io/controller-runtime@v0.19.3",
+				"mod tidy",
+				"mod edit -json",
+				"mod vendor",
+				"mod verify",
+			},
+		},
+		{
+			name:          "go mod verify fails",
+			verifyErr:     fmt.Errorf("exit status 1"),
+			expectedError: "error running go mod verify: exit status 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			writeModulesTxt(t, projectPath, "# sigs.k8s.io/controller-runtime v0.18.4\n")
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(`dependencies:
+  - package: "sigs.k8s.io/controller-runtime"
+    version: "v0.19.3"`), 0600))
+
+			var commands []string
+			goCommandFunc = func(_ bool, _ string, arg ...string) commandExecutor {
+				command := strings.Join(arg, " ")
+				commands = append(commands, command)
+				if command == "mod vendor" {
+					// simulate go mod vendor updating modules.txt
+					writeModulesTxt(t, projectPath, "# sigs.k8s.io/controller-runtime v0.19.3\n")
+				}
+				if command == "mod verify" {
+					return &MockCommandExecutor{RunErr: tt.verifyErr}
+				}
+				return &MockCommandExecutor{Outcome: `{"Require":[{"Path":"sigs.k8s.io/controller-runtime","Version":"v0.18.4"}]}`}
+			}
+
+			report, err := Upgrade(configPath, projectPath, UpgradeOptions{})
+
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommands, commands)
+			require.NotNil(t, report.Vendor)
+			assert.Equal(t, []string{"sigs.k8s.io/controller-runtime"}, report.Vendor.Updated)
+			assert.Empty(t, report.Vendor.Added)
+			assert.Empty(t, report.Vendor.Removed)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// PullRequest holds the identity of a pull request (a merge request on GitLab)
+type PullRequest struct {
+	Number int
+	URL    string
+}
+
+// PullRequestOptions holds the content of a pull request to open or update
+type PullRequestOptions struct {
+	Repository string // owner/name on GitHub, namespace/project on GitLab
+	Head       string // branch containing the changes
+	Base       string // branch the changes should be merged into
+	Title      string
+	Body       string
+	Labels     []string
+	Reviewers  []string // usernames
+}
+
+// Forge is a code hosting service able to open pull requests
+type Forge interface {
+	// CreateOrUpdatePullRequest opens a pull request from the head branch into the base branch,
+	// or updates the title, body, labels and reviewers of the open one if it already exists.
+	CreateOrUpdatePullRequest(options PullRequestOptions) (*PullRequest, error)
+}
+
+// New returns the Forge of the given kind ("github" or "gitlab"). An empty baseURL selects
+// the API of the public service.
+func New(kind, baseURL, token string) (Forge, error) {
+	switch kind {
+	case "github":
+		if baseURL == "" {
+			baseURL = GitHubURL
+		}
+		return &GitHub{client: newClient(baseURL, map[string]string{
+			"Authorization": "Bearer " + token,
+			"Accept":        "application/vnd.github+json",
+		})}, nil
+	case "gitlab":
+		if baseURL == "" {
+			baseURL = GitLabURL
+		}
+		return &GitLab{client: newClient(baseURL, map[string]string{
+			"PRIVATE-TOKEN": token,
+		})}, nil
+	default:
+		return nil, fmt.Errorf("unsupported forge %q: must be one of github, gitlab", kind)
+	}
+}
+
+// client is a minimal JSON client for REST APIs
+type client struct {
+	baseURL string
+	headers map[string]string
+	http    *http.Client
+}
+
+func newClient(baseURL string, headers map[string]string) *client {
+	return &client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		http:    http.DefaultClient,
+	}
+}
+
+// do sends a request with the JSON encoded body to the given path of the API
+// and decodes the JSON response into out, if not nil.
+func (c *client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s returned status: %s - %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+
+	return nil
+}
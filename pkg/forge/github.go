@@ -0,0 +1,79 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHubURL is the API of github.com
+const GitHubURL = "https://api.github.com"
+
+// GitHub implements Forge using the GitHub REST API
+type GitHub struct {
+	client *client
+}
+
+type githubPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g *GitHub) CreateOrUpdatePullRequest(options PullRequestOptions) (*PullRequest, error) {
+	owner, _, found := strings.Cut(options.Repository, "/")
+	if !found {
+		return nil, fmt.Errorf("invalid GitHub repository %q: must be owner/name", options.Repository)
+	}
+
+	repoPath := fmt.Sprintf("/repos/%s", options.Repository)
+
+	// look for an open pull request from the same branch
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("head", fmt.Sprintf("%s:%s", owner, options.Head))
+	query.Set("base", options.Base)
+
+	var existing []githubPullRequest
+	if err := g.client.do(http.MethodGet, fmt.Sprintf("%s/pulls?%s", repoPath, query.Encode()), nil, &existing); err != nil {
+		return nil, err
+	}
+
+	var pr githubPullRequest
+	if len(existing) > 0 {
+		update := map[string]string{
+			"title": options.Title,
+			"body":  options.Body,
+		}
+		if err := g.client.do(http.MethodPatch, fmt.Sprintf("%s/pulls/%d", repoPath, existing[0].Number), update, &pr); err != nil {
+			return nil, err
+		}
+	} else {
+		create := map[string]string{
+			"title": options.Title,
+			"body":  options.Body,
+			"head":  options.Head,
+			"base":  options.Base,
+		}
+		if err := g.client.do(http.MethodPost, fmt.Sprintf("%s/pulls", repoPath), create, &pr); err != nil {
+			return nil, err
+		}
+	}
+
+	// both endpoints only add what is missing, so they are safe to call again on an existing pull request
+	if len(options.Labels) > 0 {
+		labels := map[string][]string{"labels": options.Labels}
+		if err := g.client.do(http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", repoPath, pr.Number), labels, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.Reviewers) > 0 {
+		reviewers := map[string][]string{"reviewers": options.Reviewers}
+		if err := g.client.do(http.MethodPost, fmt.Sprintf("%s/pulls/%d/requested_reviewers", repoPath, pr.Number), reviewers, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PullRequest{Number: pr.Number, URL: pr.HTMLURL}, nil
+}
@@ -0,0 +1,146 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request is a request received by the fake forge
+type request struct {
+	Method string
+	Path   string
+	Body   map[string]any
+}
+
+// newFakeForge starts a server recording the requests it receives and replying
+// with the response registered for "<method> <path>", or with an empty JSON object.
+func newFakeForge(t *testing.T, responses map[string]string) (*httptest.Server, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+
+		received := request{Method: r.Method, Path: path}
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if len(data) > 0 {
+			require.NoError(t, json.Unmarshal(data, &received.Body))
+		}
+		requests = append(requests, received)
+
+		response, found := responses[fmt.Sprintf("%s %s", r.Method, path)]
+		if !found {
+			response = "{}"
+		}
+		fmt.Fprint(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestGitHubCreateOrUpdatePullRequest(t *testing.T) {
+	options := PullRequestOptions{
+		Repository: "codeready-toolchain/member-operator",
+		Head:       "upgrade-deps",
+		Base:       "master",
+		Title:      "Upgrade 2 Go dependencies",
+		Body:       "## Dependency upgrades",
+		Labels:     []string{"dependencies"},
+		Reviewers:  []string{"alice"},
+	}
+	listPath := "/repos/codeready-toolchain/member-operator/pulls?base=master&head=codeready-toolchain%3Aupgrade-deps&state=open"
+
+	t.Run("create pull request", func(t *testing.T) {
+		server, requests := newFakeForge(t, map[string]string{
+			"GET " + listPath: `[]`,
+			"POST /repos/codeready-toolchain/member-operator/pulls": `{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}`,
+		})
+		forge, err := New("github", server.URL, "token")
+		require.NoError(t, err)
+
+		pr, err := forge.CreateOrUpdatePullRequest(options)
+
+		require.NoError(t, err)
+		assert.Equal(t, &PullRequest{Number: 42, URL: "https://github.com/codeready-toolchain/member-operator/pull/42"}, pr)
+		assert.Equal(t, []request{
+			{Method: http.MethodGet, Path: listPath},
+			{Method: http.MethodPost, Path: "/repos/codeready-toolchain/member-operator/pulls", Body: map[string]any{
+				"title": "Upgrade 2 Go dependencies",
+				"body":  "## Dependency upgrades",
+				"head":  "upgrade-deps",
+				"base":  "master",
+			}},
+			{Method: http.MethodPost, Path: "/repos/codeready-toolchain/member-operator/issues/42/labels", Body: map[string]any{
+				"labels": []any{"dependencies"},
+			}},
+			{Method: http.MethodPost, Path: "/repos/codeready-toolchain/member-operator/pulls/42/requested_reviewers", Body: map[string]any{
+				"reviewers": []any{"alice"},
+			}},
+		}, *requests)
+	})
+
+	t.Run("update existing pull request", func(t *testing.T) {
+		server, requests := newFakeForge(t, map[string]string{
+			"GET " + listPath: `[{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}]`,
+			"PATCH /repos/codeready-toolchain/member-operator/pulls/42": `{"number":42,"html_url":"https://github.com/codeready-toolchain/member-operator/pull/42"}`,
+		})
+		forge, err := New("github", server.URL, "token")
+		require.NoError(t, err)
+
+		pr, err := forge.CreateOrUpdatePullRequest(PullRequestOptions{
+			Repository: options.Repository,
+			Head:       options.Head,
+			Base:       options.Base,
+			Title:      options.Title,
+			Body:       options.Body,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 42, pr.Number)
+		assert.Equal(t, []request{
+			{Method: http.MethodGet, Path: listPath},
+			{Method: http.MethodPatch, Path: "/repos/codeready-toolchain/member-operator/pulls/42", Body: map[string]any{
+				"title": "Upgrade 2 Go dependencies",
+				"body":  "## Dependency upgrades",
+			}},
+		}, *requests)
+	})
+
+	t.Run("API error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		forge, err := New("github", server.URL, "token")
+		require.NoError(t, err)
+
+		_, err = forge.CreateOrUpdatePullRequest(options)
+
+		require.EqualError(t, err, "GET "+listPath+` returned status: 401 Unauthorized - {"message":"Bad credentials"}`)
+	})
+
+	t.Run("invalid repository", func(t *testing.T) {
+		forge, err := New("github", "", "token")
+		require.NoError(t, err)
+
+		_, err = forge.CreateOrUpdatePullRequest(PullRequestOptions{Repository: "member-operator"})
+
+		require.EqualError(t, err, `invalid GitHub repository "member-operator": must be owner/name`)
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("bitbucket", "", "token")
+	require.EqualError(t, err, `unsupported forge "bitbucket": must be one of github, gitlab`)
+}
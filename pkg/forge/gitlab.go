@@ -0,0 +1,98 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLabURL is the API of gitlab.com
+const GitLabURL = "https://gitlab.com/api/v4"
+
+// GitLab implements Forge using the GitLab REST API, where pull requests are called merge requests
+type GitLab struct {
+	client *client
+}
+
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+type gitlabUser struct {
+	ID int `json:"id"`
+}
+
+func (g *GitLab) CreateOrUpdatePullRequest(options PullRequestOptions) (*PullRequest, error) {
+	projectPath := fmt.Sprintf("/projects/%s", url.PathEscape(options.Repository))
+
+	// merge requests reference reviewers by user id, not username
+	reviewerIDs, err := g.userIDs(options.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	// look for an open merge request from the same branch
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("source_branch", options.Head)
+	query.Set("target_branch", options.Base)
+
+	var existing []gitlabMergeRequest
+	if err := g.client.do(http.MethodGet, fmt.Sprintf("%s/merge_requests?%s", projectPath, query.Encode()), nil, &existing); err != nil {
+		return nil, err
+	}
+
+	var mr gitlabMergeRequest
+	if len(existing) > 0 {
+		update := map[string]any{
+			"title":       options.Title,
+			"description": options.Body,
+		}
+		if len(options.Labels) > 0 {
+			update["add_labels"] = strings.Join(options.Labels, ",")
+		}
+		if len(reviewerIDs) > 0 {
+			update["reviewer_ids"] = reviewerIDs
+		}
+		if err := g.client.do(http.MethodPut, fmt.Sprintf("%s/merge_requests/%d", projectPath, existing[0].IID), update, &mr); err != nil {
+			return nil, err
+		}
+	} else {
+		create := map[string]any{
+			"title":         options.Title,
+			"description":   options.Body,
+			"source_branch": options.Head,
+			"target_branch": options.Base,
+		}
+		if len(options.Labels) > 0 {
+			create["labels"] = strings.Join(options.Labels, ",")
+		}
+		if len(reviewerIDs) > 0 {
+			create["reviewer_ids"] = reviewerIDs
+		}
+		if err := g.client.do(http.MethodPost, fmt.Sprintf("%s/merge_requests", projectPath), create, &mr); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PullRequest{Number: mr.IID, URL: mr.WebURL}, nil
+}
+
+// userIDs resolves the given usernames to GitLab user ids.
+func (g *GitLab) userIDs(usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		var users []gitlabUser
+		if err := g.client.do(http.MethodGet, "/users?username="+url.QueryEscape(username), nil, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("GitLab user %s not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+
+	return ids, nil
+}
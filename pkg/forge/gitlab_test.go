@@ -0,0 +1,83 @@
+package forge
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitLabCreateOrUpdatePullRequest(t *testing.T) {
+	options := PullRequestOptions{
+		Repository: "platform/operators/member-operator",
+		Head:       "upgrade-deps",
+		Base:       "main",
+		Title:      "Upgrade 2 Go dependencies",
+		Body:       "## Dependency upgrades",
+		Labels:     []string{"dependencies", "automated"},
+		Reviewers:  []string{"alice"},
+	}
+	projectPath := "/projects/platform%2Foperators%2Fmember-operator"
+	listPath := projectPath + "/merge_requests?source_branch=upgrade-deps&state=opened&target_branch=main"
+
+	t.Run("create merge request", func(t *testing.T) {
+		server, requests := newFakeForge(t, map[string]string{
+			"GET /users?username=alice":               `[{"id":7}]`,
+			"GET " + listPath:                         `[]`,
+			"POST " + projectPath + "/merge_requests": `{"iid":3,"web_url":"https://gitlab.com/platform/operators/member-operator/-/merge_requests/3"}`,
+		})
+		forge, err := New("gitlab", server.URL, "token")
+		require.NoError(t, err)
+
+		pr, err := forge.CreateOrUpdatePullRequest(options)
+
+		require.NoError(t, err)
+		assert.Equal(t, &PullRequest{Number: 3, URL: "https://gitlab.com/platform/operators/member-operator/-/merge_requests/3"}, pr)
+		assert.Equal(t, []request{
+			{Method: http.MethodGet, Path: "/users?username=alice"},
+			{Method: http.MethodGet, Path: listPath},
+			{Method: http.MethodPost, Path: projectPath + "/merge_requests", Body: map[string]any{
+				"title":         "Upgrade 2 Go dependencies",
+				"description":   "## Dependency upgrades",
+				"source_branch": "upgrade-deps",
+				"target_branch": "main",
+				"labels":        "dependencies,automated",
+				"reviewer_ids":  []any{float64(7)},
+			}},
+		}, *requests)
+	})
+
+	t.Run("update existing merge request", func(t *testing.T) {
+		server, requests := newFakeForge(t, map[string]string{
+			"GET /users?username=alice":                `[{"id":7}]`,
+			"GET " + listPath:                          `[{"iid":3,"web_url":"https://gitlab.com/platform/operators/member-operator/-/merge_requests/3"}]`,
+			"PUT " + projectPath + "/merge_requests/3": `{"iid":3,"web_url":"https://gitlab.com/platform/operators/member-operator/-/merge_requests/3"}`,
+		})
+		forge, err := New("gitlab", server.URL, "token")
+		require.NoError(t, err)
+
+		pr, err := forge.CreateOrUpdatePullRequest(options)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, pr.Number)
+		assert.Equal(t, request{Method: http.MethodPut, Path: projectPath + "/merge_requests/3", Body: map[string]any{
+			"title":        "Upgrade 2 Go dependencies",
+			"description":  "## Dependency upgrades",
+			"add_labels":   "dependencies,automated",
+			"reviewer_ids": []any{float64(7)},
+		}}, (*requests)[2])
+	})
+
+	t.Run("unknown reviewer", func(t *testing.T) {
+		server, _ := newFakeForge(t, map[string]string{
+			"GET /users?username=alice": `[]`,
+		})
+		forge, err := New("gitlab", server.URL, "token")
+		require.NoError(t, err)
+
+		_, err = forge.CreateOrUpdatePullRequest(options)
+
+		require.EqualError(t, err, "GitLab user alice not found")
+	})
+}